@@ -0,0 +1,242 @@
+package challenge04
+
+import (
+	"container/heap"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CycleError is returned by TopologicalSort when the graph has a cycle.
+type CycleError struct {
+	// Cycle lists the nodes of one cycle in edge order; the last node has an edge
+	// back to the first.
+	Cycle []int
+}
+
+func (e *CycleError) Error() string {
+	nodes := make([]string, len(e.Cycle)+1)
+	for i, u := range e.Cycle {
+		nodes[i] = strconv.Itoa(u)
+	}
+	nodes[len(e.Cycle)] = nodes[0]
+	return "graph has a cycle: " + strings.Join(nodes, " -> ")
+}
+
+// ConnectedComponents returns the weakly connected components of the graph,
+// treating every edge as undirected. The edges are split across numWorkers
+// goroutines that merge components in a shared, lock-free union-find.
+// Every component is sorted, and components are ordered by their smallest node.
+func ConnectedComponents(graph map[int][]int, numWorkers int) [][]int {
+	nodes := graphNodes(graph)
+	index := make(map[int]int, len(nodes))
+	for i, u := range nodes {
+		index[u] = i
+	}
+
+	type edge struct{ u, v int }
+	edges := make([]edge, 0, len(graph))
+	for u, neighbors := range graph {
+		for _, v := range neighbors {
+			edges = append(edges, edge{index[u], index[v]})
+		}
+	}
+
+	uf := newUnionFind(len(nodes))
+	numWorkers = max(numWorkers, 1)
+	var wg sync.WaitGroup
+	for w := range numWorkers {
+		wg.Go(func() {
+			for _, e := range edges[w*len(edges)/numWorkers : (w+1)*len(edges)/numWorkers] {
+				uf.union(e.u, e.v)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Nodes are sorted, and every root is the smallest index of its component,
+	// so components come out sorted and in order of their smallest node.
+	var components [][]int
+	componentOf := make(map[int]int)
+	for i, u := range nodes {
+		root := uf.find(i)
+		c, ok := componentOf[root]
+		if !ok {
+			c = len(components)
+			componentOf[root] = c
+			components = append(components, nil)
+		}
+		components[c] = append(components[c], u)
+	}
+	return components
+}
+
+// FindCycle returns the nodes of a directed cycle in edge order, or nil if the
+// graph is acyclic. A self-loop is a cycle of one node.
+func FindCycle(graph map[int][]int) []int {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[int]int)
+
+	type frame struct {
+		node int
+		next int // index of the next neighbor to explore
+	}
+
+	for _, root := range graphNodes(graph) {
+		if state[root] != unvisited {
+			continue
+		}
+		// Iterative DFS, so that long paths don't overflow the goroutine stack.
+		stack := []frame{{node: root}}
+		state[root] = onStack
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.next == len(graph[top.node]) {
+				state[top.node] = done
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			v := graph[top.node][top.next]
+			top.next++
+
+			switch state[v] {
+			case unvisited:
+				state[v] = onStack
+				stack = append(stack, frame{node: v})
+			case onStack:
+				// The back edge closes the cycle formed by the stack from v upwards.
+				i := slices.IndexFunc(stack, func(f frame) bool { return f.node == v })
+				cycle := make([]int, 0, len(stack)-i)
+				for _, f := range stack[i:] {
+					cycle = append(cycle, f.node)
+				}
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// TopologicalSort orders the nodes so that for every edge u -> v, u comes before v.
+// Among the nodes that are ready at any point, the smallest comes first, so the
+// order is deterministic. If the graph has a cycle, it returns a *CycleError.
+func TopologicalSort(graph map[int][]int) ([]int, error) {
+	nodes := graphNodes(graph)
+	inDegree := make(map[int]int, len(nodes))
+	for _, neighbors := range graph {
+		for _, v := range neighbors {
+			inDegree[v]++
+		}
+	}
+
+	ready := &intHeap{}
+	for _, u := range nodes {
+		if inDegree[u] == 0 {
+			heap.Push(ready, u)
+		}
+	}
+
+	order := make([]int, 0, len(nodes))
+	for ready.Len() > 0 {
+		u := heap.Pop(ready).(int)
+		order = append(order, u)
+		for _, v := range graph[u] {
+			inDegree[v]--
+			if inDegree[v] == 0 {
+				heap.Push(ready, v)
+			}
+		}
+	}
+
+	if len(order) < len(nodes) {
+		return nil, &CycleError{Cycle: FindCycle(graph)}
+	}
+	return order, nil
+}
+
+// graphNodes returns every node of the graph, including nodes that only appear
+// as neighbors, in increasing order.
+func graphNodes(graph map[int][]int) []int {
+	seen := make(map[int]struct{}, len(graph))
+	for u, neighbors := range graph {
+		seen[u] = struct{}{}
+		for _, v := range neighbors {
+			seen[v] = struct{}{}
+		}
+	}
+	nodes := make([]int, 0, len(seen))
+	for u := range seen {
+		nodes = append(nodes, u)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// unionFind is a disjoint-set forest that is safe for concurrent use. Roots are
+// only linked under smaller roots, with a compare-and-swap, so that concurrent
+// unions cannot form a cycle, and every root is the smallest element of its set.
+type unionFind struct {
+	parent []atomic.Int64
+}
+
+func newUnionFind(n int) *unionFind {
+	uf := &unionFind{parent: make([]atomic.Int64, n)}
+	for i := range uf.parent {
+		uf.parent[i].Store(int64(i))
+	}
+	return uf
+}
+
+func (uf *unionFind) find(x int) int {
+	for {
+		p := int(uf.parent[x].Load())
+		if p == x {
+			return x
+		}
+		// Path halving; losing the race to another writer is harmless.
+		gp := uf.parent[p].Load()
+		uf.parent[x].CompareAndSwap(int64(p), gp)
+		x = int(gp)
+	}
+}
+
+func (uf *unionFind) union(a, b int) {
+	for {
+		ra, rb := uf.find(a), uf.find(b)
+		if ra == rb {
+			return
+		}
+		if ra < rb {
+			ra, rb = rb, ra
+		}
+		// Fails if ra stopped being a root in the meantime; retry from the new roots.
+		if uf.parent[ra].CompareAndSwap(int64(ra), int64(rb)) {
+			return
+		}
+	}
+}
+
+// intHeap is a min-heap of ints.
+type intHeap []int
+
+func (h *intHeap) Len() int           { return len(*h) }
+func (h *intHeap) Less(i, j int) bool { return (*h)[i] < (*h)[j] }
+func (h *intHeap) Swap(i, j int)      { (*h)[i], (*h)[j] = (*h)[j], (*h)[i] }
+
+func (h *intHeap) Push(x any) {
+	*h = append(*h, x.(int))
+}
+
+func (h *intHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,146 @@
+package challenge04
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// assertCycle checks that cycle is a non-empty sequence of edges of graph that ends where it started.
+func assertCycle(t *testing.T, graph map[int][]int, cycle []int) {
+	t.Helper()
+	if len(cycle) == 0 {
+		t.Fatal("Expected a cycle, got none")
+	}
+	for i, u := range cycle {
+		v := cycle[(i+1)%len(cycle)]
+		if !slices.Contains(graph[u], v) {
+			t.Errorf("Cycle %v uses missing edge %d -> %d", cycle, u, v)
+		}
+	}
+}
+
+func TestConnectedComponents(t *testing.T) {
+	testCases := []struct {
+		name     string
+		graph    map[int][]int
+		expected [][]int
+	}{
+		{
+			name:     "Empty graph",
+			graph:    map[int][]int{},
+			expected: nil,
+		},
+		{
+			name:     "Sample graph is weakly connected",
+			graph:    buildSampleGraph(),
+			expected: [][]int{{0, 1, 2, 3, 4, 5}},
+		},
+		{
+			name: "Disconnected components",
+			graph: map[int][]int{
+				9: {1},
+				2: {3},
+				4: {},
+				7: {3, 8},
+			},
+			expected: [][]int{{1, 9}, {2, 3, 7, 8}, {4}},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, numWorkers := range []int{0, 1, 4} {
+			t.Run(tc.name, func(t *testing.T) {
+				components := ConnectedComponents(tc.graph, numWorkers)
+				if !reflect.DeepEqual(components, tc.expected) {
+					t.Errorf("Expected %v, got %v", tc.expected, components)
+				}
+			})
+		}
+	}
+
+	t.Run("Matches sequential union-find on a random graph", func(t *testing.T) {
+		graph := buildRandomGraph(5000, 4000, 4)
+		expected := ConnectedComponents(graph, 1)
+		for range 5 {
+			components := ConnectedComponents(graph, 8)
+			if !reflect.DeepEqual(components, expected) {
+				t.Fatalf("Concurrent components differ from sequential ones")
+			}
+		}
+	})
+}
+
+func TestFindCycle(t *testing.T) {
+	t.Run("Acyclic", func(t *testing.T) {
+		if cycle := FindCycle(buildSampleGraph()); cycle != nil {
+			t.Errorf("Expected no cycle, got %v", cycle)
+		}
+	})
+
+	t.Run("Self-loop", func(t *testing.T) {
+		if cycle := FindCycle(map[int][]int{0: {1}, 1: {1}}); !reflect.DeepEqual(cycle, []int{1}) {
+			t.Errorf("Expected [1], got %v", cycle)
+		}
+	})
+
+	t.Run("Cycle reachable through a path", func(t *testing.T) {
+		graph := map[int][]int{0: {1}, 1: {2}, 2: {3}, 3: {4}, 4: {2}}
+		cycle := FindCycle(graph)
+		assertCycle(t, graph, cycle)
+		if !reflect.DeepEqual(cycle, []int{2, 3, 4}) {
+			t.Errorf("Expected [2 3 4], got %v", cycle)
+		}
+	})
+
+	t.Run("Long path", func(t *testing.T) {
+		graph := buildLargeLinearGraph(100000)
+		graph[99999] = []int{0}
+		cycle := FindCycle(graph)
+		assertCycle(t, graph, cycle)
+		if len(cycle) != 100000 {
+			t.Errorf("Expected a cycle of 100000 nodes, got %d", len(cycle))
+		}
+	})
+}
+
+func TestTopologicalSort(t *testing.T) {
+	t.Run("Acyclic", func(t *testing.T) {
+		order, err := TopologicalSort(buildSampleGraph())
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := []int{0, 1, 5, 2, 3, 4}
+		if !reflect.DeepEqual(order, expected) {
+			t.Errorf("Expected %v, got %v", expected, order)
+		}
+	})
+
+	t.Run("Includes nodes only reachable as neighbors", func(t *testing.T) {
+		order, err := TopologicalSort(map[int][]int{3: {1, 2}, 2: {1}})
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if !reflect.DeepEqual(order, []int{3, 2, 1}) {
+			t.Errorf("Expected [3 2 1], got %v", order)
+		}
+	})
+
+	t.Run("Cyclic", func(t *testing.T) {
+		graph := map[int][]int{0: {1}, 1: {2}, 2: {0, 3}, 3: {}}
+		order, err := TopologicalSort(graph)
+		if order != nil {
+			t.Errorf("Expected no order, got %v", order)
+		}
+
+		var cycleErr *CycleError
+		if !errors.As(err, &cycleErr) {
+			t.Fatalf("Expected CycleError but got %T: %v", err, err)
+		}
+		assertCycle(t, graph, cycleErr.Cycle)
+		if err.Error() != "graph has a cycle: 0 -> 1 -> 2 -> 0" {
+			t.Errorf("Unexpected error message %q", err.Error())
+		}
+	})
+}
@@ -1,6 +1,58 @@
 package challenge04
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// ctxCheckInterval is how many nodes a traversal visits between cancellation checks.
+const ctxCheckInterval = 256
+
+// IncompleteQueriesError is returned when some queries were not processed,
+// either because the context was done before their traversal completed, or
+// because there were no workers to process them.
+type IncompleteQueriesError struct {
+	// Unprocessed lists the starting nodes without a result, in query order.
+	Unprocessed []int
+	cause       error
+}
+
+func (e *IncompleteQueriesError) Error() string {
+	msg := fmt.Sprintf("%d queries not processed", len(e.Unprocessed))
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+func (e *IncompleteQueriesError) Unwrap() error {
+	return e.cause
+}
+
+// BFSResult is the BFS traversal from Start.
+type BFSResult struct {
+	Start int
+	// Order lists the visited nodes in BFS order.
+	Order []int
+	// Depths[i] is the number of edges between Start and Order[i].
+	Depths []int
+}
+
+// Levels groups the visited nodes by depth, so that Levels()[d] lists the nodes
+// at distance d from Start, in BFS order.
+func (r BFSResult) Levels() [][]int {
+	var levels [][]int
+	for i, node := range r.Order {
+		d := r.Depths[i]
+		if d == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], node)
+	}
+	return levels
+}
 
 // ConcurrentBFSQueries concurrently processes BFS queries on the provided graph.
 // - graph: adjacency list, e.g., graph[u] = []int{v1, v2, ...}
@@ -10,31 +62,158 @@ import "sync"
 // Return a map from the query (starting node) to the BFS order as a slice of nodes.
 // YOU MUST use concurrency (goroutines + channels) to pass the performance tests.
 func ConcurrentBFSQueries(graph map[int][]int, queries []int, numWorkers int) map[int][]int {
-	type MapEntry struct {
-		start int
-		bfs   []int
+	res, _ := fanOut(
+		context.Background(),
+		queries,
+		numWorkers,
+		func(_ context.Context, start int) ([]int, error) {
+			return bfs(graph, start), nil
+		},
+	)
+	return res
+}
+
+// ConcurrentBFSQueriesContext is like ConcurrentBFSQueries, but stops the workers
+// once ctx is done. It returns the results computed so far, and if any query was
+// not processed, an *IncompleteQueriesError that wraps ctx.Err().
+func ConcurrentBFSQueriesContext(
+	ctx context.Context,
+	graph map[int][]int,
+	queries []int,
+	numWorkers int,
+) (map[int][]int, error) {
+	res, unprocessed := fanOut(
+		ctx,
+		queries,
+		numWorkers,
+		func(ctx context.Context, start int) ([]int, error) {
+			result, err := bfsContext(ctx, graph, start, newBFSOptions())
+			return result.Order, err
+		},
+	)
+	if len(unprocessed) > 0 {
+		return res, &IncompleteQueriesError{Unprocessed: unprocessed, cause: ctx.Err()}
+	}
+	return res, nil
+}
+
+// StreamBFSQueries is like ConcurrentBFSQueriesContext, but yields each
+// query's result as soon as its worker finishes, in completion order. Stopping
+// the iteration early, or ctx being done, stops the workers; queries that were
+// not processed by then are not yielded.
+func StreamBFSQueries(
+	ctx context.Context,
+	graph map[int][]int,
+	queries []int,
+	numWorkers int,
+) iter.Seq2[int, BFSResult] {
+	return stream(
+		ctx,
+		queries,
+		numWorkers,
+		func(ctx context.Context, start int) (BFSResult, error) {
+			return bfsContext(ctx, graph, start, newBFSOptions(withDepths()))
+		},
+	)
+}
+
+// fanOut runs work for every query on a pool of numWorkers goroutines, and
+// collects the results keyed by query. Duplicate queries are computed once per
+// occurrence, and the last result wins.
+//
+// Once ctx is done, no more queries are handed out and fanOut returns after the
+// in-flight work returns. Queries without a result, either because they were
+// never started or because work returned an error, are returned in query order.
+func fanOut[Q comparable, R any](
+	ctx context.Context,
+	queries []Q,
+	numWorkers int,
+	work func(context.Context, Q) (R, error),
+) (map[Q]R, []Q) {
+	res := make(map[Q]R, len(queries))
+	for q, r := range stream(ctx, queries, numWorkers, work) {
+		res[q] = r
 	}
+	return res, unprocessedQueries(queries, res)
+}
+
+// stream returns an iterator that runs work for every query on a pool of
+// numWorkers goroutines, and yields each query with its result as soon as it is
+// available. Queries for which work returns an error are skipped.
+func stream[Q comparable, R any](
+	parent context.Context,
+	queries []Q,
+	numWorkers int,
+	work func(context.Context, Q) (R, error),
+) iter.Seq2[Q, R] {
+	return func(yield func(Q, R) bool) {
+		if numWorkers <= 0 {
+			return
+		}
 
-	starts := make(chan int)
-	results := make(chan MapEntry)
+		// Cancelling interrupts in-flight work when the caller stops early.
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+		done := make(chan struct{})
+		defer close(done)
+
+		for r := range startWorkers(ctx, queries, numWorkers, work, done) {
+			if !yield(r.query, r.result) {
+				return
+			}
+		}
+	}
+}
+
+type queryResult[Q comparable, R any] struct {
+	query  Q
+	result R
+}
+
+// startWorkers starts numWorkers goroutines that run work for every query, and
+// returns the channel on which they send the results. The channel is closed
+// once all workers exit. Closing done stops handing out queries, and makes the
+// workers drop their results instead of blocking on a receiver that is gone.
+func startWorkers[Q comparable, R any](
+	ctx context.Context,
+	queries []Q,
+	numWorkers int,
+	work func(context.Context, Q) (R, error),
+	done <-chan struct{},
+) <-chan queryResult[Q, R] {
+	jobs := make(chan Q)
+	results := make(chan queryResult[Q, R])
 
 	var wg sync.WaitGroup
 
 	// Start workers
 	for range numWorkers {
 		wg.Go(func() {
-			for s := range starts {
-				results <- MapEntry{start: s, bfs: bfs(graph, s)}
+			for q := range jobs {
+				r, err := work(ctx, q)
+				if err != nil {
+					continue
+				}
+				select {
+				case results <- queryResult[Q, R]{query: q, result: r}:
+				case <-done:
+				}
 			}
 		})
 	}
 
 	// Send queries
 	go func() {
+		defer close(jobs)
 		for _, q := range queries {
-			starts <- q
+			select {
+			case jobs <- q:
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
 		}
-		close(starts)
 	}()
 
 	go func() {
@@ -42,35 +221,98 @@ func ConcurrentBFSQueries(graph map[int][]int, queries []int, numWorkers int) ma
 		close(results)
 	}()
 
-	// Collect results
-	res := make(map[int][]int, len(queries))
-	for r := range results {
-		res[r.start] = r.bfs
-	}
+	return results
+}
 
-	return res
+func unprocessedQueries[Q comparable, R any](queries []Q, res map[Q]R) []Q {
+	var unprocessed []Q
+	seen := make(map[Q]struct{})
+	for _, q := range queries {
+		if _, ok := res[q]; ok {
+			continue
+		}
+		if _, ok := seen[q]; !ok {
+			seen[q] = struct{}{}
+			unprocessed = append(unprocessed, q)
+		}
+	}
+	return unprocessed
 }
 
 func bfs(graph map[int][]int, start int) []int {
-	visited := make(map[int]struct{})
-	queue := []int{start}
-	result := make([]int, 0)
+	// The background context is never done, so there is no error to check.
+	result, _ := bfsContext(context.Background(), graph, start, newBFSOptions())
+	return result.Order
+}
 
-	visited[start] = struct{}{}
+// bfsContext returns the BFS traversal from start restricted by opts, or
+// ctx.Err() if ctx is done before the traversal completes. The result has
+// Depths only if opts ask for them.
+func bfsContext(
+	ctx context.Context,
+	graph map[int][]int,
+	start int,
+	opts bfsOptions,
+) (BFSResult, error) {
+	// Pruned nodes are marked visited too, so that the filter runs once per
+	// node.
+	visited := map[int]struct{}{start: {}}
+	queue := []int{start}
+	result := BFSResult{Start: start, Order: make([]int, 0)}
+	if opts.depths {
+		result.Depths = make([]int, 0)
+	}
 
+	// The queue holds the rest of the level at depth d, then the nodes of the
+	// next level, so counting them is enough to know the depth of every node.
+	d, inLevel, nextLevel := 0, 1, 0
 	for len(queue) > 0 {
+		if len(result.Order)%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return BFSResult{}, err
+			}
+		}
+		if inLevel == 0 {
+			d, inLevel, nextLevel = d+1, nextLevel, 0
+		}
+
 		node := queue[0]
 		queue = queue[1:]
+		inLevel--
 
-		result = append(result, node)
+		result.Order = append(result.Order, node)
+		if opts.depths {
+			result.Depths = append(result.Depths, d)
+		}
 
-		for _, neighbor := range graph[node] {
-			if _, ok := visited[neighbor]; !ok {
-				visited[neighbor] = struct{}{}
-				queue = append(queue, neighbor)
-			}
+		if opts.done(node) {
+			break
+		}
+		if opts.expand(d) {
+			n := len(queue)
+			queue = enqueueNeighbors(queue, graph[node], visited, opts)
+			nextLevel += len(queue) - n
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// enqueueNeighbors appends the neighbors that are not visited yet and that opts
+// keep to queue, marking all of them visited.
+func enqueueNeighbors(
+	queue, neighbors []int,
+	visited map[int]struct{},
+	opts bfsOptions,
+) []int {
+	for _, neighbor := range neighbors {
+		if _, ok := visited[neighbor]; ok {
+			continue
+		}
+		visited[neighbor] = struct{}{}
+		if opts.visit(neighbor) {
+			queue = append(queue, neighbor)
+		}
+	}
+	return queue
 }
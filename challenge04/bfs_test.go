@@ -1,6 +1,8 @@
 package challenge04
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"sync"
 	"testing"
@@ -395,3 +397,348 @@ func TestCorrectnessWithComplexGraphs(t *testing.T) {
 		}
 	})
 }
+
+func TestConcurrentBFSQueriesContext(t *testing.T) {
+	t.Run("Completes without error", func(t *testing.T) {
+		graph := buildSampleGraph()
+		queries := []int{0, 1, 5}
+		results, err := ConcurrentBFSQueriesContext(context.Background(), graph, queries, 2)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		for _, start := range queries {
+			refOrder := bfsReference(graph, start)
+			if !reflect.DeepEqual(results[start], refOrder) {
+				t.Errorf("For start %d, expected %v, got %v", start, refOrder, results[start])
+			}
+		}
+	})
+
+	t.Run("Already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		queries := []int{0, 1, 0, 5}
+		results, err := ConcurrentBFSQueriesContext(ctx, buildSampleGraph(), queries, 2)
+
+		var incomplete *IncompleteQueriesError
+		if !errors.As(err, &incomplete) {
+			t.Fatalf("Expected IncompleteQueriesError but got %T: %v", err, err)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected error to wrap context.Canceled, got %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected no results, got %v", results)
+		}
+		if !reflect.DeepEqual(incomplete.Unprocessed, []int{0, 1, 5}) {
+			t.Errorf("Expected unprocessed [0 1 5], got %v", incomplete.Unprocessed)
+		}
+	})
+
+	t.Run("Deadline returns partial results", func(t *testing.T) {
+		graph := buildLargeLinearGraph(200000)
+		queries := make([]int, 200)
+		for i := range queries {
+			queries[i] = i
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		results, err := ConcurrentBFSQueriesContext(ctx, graph, queries, 2)
+
+		var incomplete *IncompleteQueriesError
+		if !errors.As(err, &incomplete) {
+			t.Fatalf("Expected IncompleteQueriesError but got %T: %v", err, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected error to wrap context.DeadlineExceeded, got %v", err)
+		}
+		if len(results)+len(incomplete.Unprocessed) != len(queries) {
+			t.Errorf("Expected %d results and unprocessed queries, got %d + %d",
+				len(queries), len(results), len(incomplete.Unprocessed))
+		}
+		for _, start := range incomplete.Unprocessed {
+			if _, ok := results[start]; ok {
+				t.Errorf("Start %d reported as unprocessed but has a result", start)
+			}
+		}
+		for start, order := range results {
+			if len(order) != len(graph)-start {
+				t.Errorf("Start %d: expected %d nodes, got %d", start, len(graph)-start, len(order))
+			}
+		}
+	})
+
+	t.Run("Zero workers", func(t *testing.T) {
+		results, err := ConcurrentBFSQueriesContext(context.Background(), buildSampleGraph(), []int{0}, 0)
+
+		var incomplete *IncompleteQueriesError
+		if !errors.As(err, &incomplete) || !reflect.DeepEqual(incomplete.Unprocessed, []int{0}) {
+			t.Errorf("Expected query 0 to be unprocessed, got %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected no results, got %v", results)
+		}
+	})
+}
+
+func TestStreamBFSQueries(t *testing.T) {
+	t.Run("Yields every query", func(t *testing.T) {
+		graph := buildSampleGraph()
+		queries := []int{0, 1, 2, 3, 4, 5}
+
+		seen := make(map[int]BFSResult)
+		for start, result := range StreamBFSQueries(context.Background(), graph, queries, 3) {
+			if _, ok := seen[start]; ok {
+				t.Errorf("Start %d yielded more than once", start)
+			}
+			seen[start] = result
+		}
+
+		if len(seen) != len(queries) {
+			t.Fatalf("Expected %d results, got %d", len(queries), len(seen))
+		}
+		for _, start := range queries {
+			refOrder := bfsReference(graph, start)
+			if !reflect.DeepEqual(seen[start].Order, refOrder) {
+				t.Errorf("For start %d, expected %v, got %v", start, refOrder, seen[start].Order)
+			}
+			if seen[start].Start != start {
+				t.Errorf("Expected result Start %d, got %d", start, seen[start].Start)
+			}
+		}
+	})
+
+	t.Run("Depths and levels", func(t *testing.T) {
+		graph := buildSampleGraph()
+
+		var result BFSResult
+		for _, r := range StreamBFSQueries(context.Background(), graph, []int{0}, 1) {
+			result = r
+		}
+
+		expectedDepths := []int{0, 1, 1, 2, 3}
+		if !reflect.DeepEqual(result.Depths, expectedDepths) {
+			t.Errorf("Expected depths %v, got %v", expectedDepths, result.Depths)
+		}
+		expectedLevels := [][]int{{0}, {1, 2}, {3}, {4}}
+		if !reflect.DeepEqual(result.Levels(), expectedLevels) {
+			t.Errorf("Expected levels %v, got %v", expectedLevels, result.Levels())
+		}
+	})
+
+	t.Run("Early break", func(t *testing.T) {
+		graph := buildLargeLinearGraph(10000)
+		queries := make([]int, 100)
+		for i := range queries {
+			queries[i] = i
+		}
+
+		count := 0
+		for range StreamBFSQueries(context.Background(), graph, queries, 4) {
+			count++
+			break
+		}
+
+		if count != 1 {
+			t.Errorf("Expected 1 result before break, got %d", count)
+		}
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		for start := range StreamBFSQueries(ctx, buildSampleGraph(), []int{0, 1}, 2) {
+			t.Errorf("Did not expect a result, got one for start %d", start)
+		}
+	})
+}
+
+func TestBFSCache(t *testing.T) {
+	t.Run("Matches uncached results", func(t *testing.T) {
+		graph := buildSampleGraph()
+		cache := NewBFSCache(graph)
+		queries := []int{0, 1, 0, 5, 1}
+
+		for range 2 {
+			results := cache.ConcurrentBFSQueries(queries, 2)
+			if len(results) != 3 {
+				t.Errorf("Expected 3 unique results, got %d", len(results))
+			}
+			for _, start := range queries {
+				refOrder := bfsReference(graph, start)
+				if !reflect.DeepEqual(results[start], refOrder) {
+					t.Errorf("For start %d, expected %v, got %v", start, refOrder, results[start])
+				}
+			}
+		}
+
+		if cache.Len() != 3 {
+			t.Errorf("Expected 3 cached traversals, got %d", cache.Len())
+		}
+	})
+
+	t.Run("Serves cached starts without workers", func(t *testing.T) {
+		cache := NewBFSCache(buildSampleGraph())
+		cache.ConcurrentBFSQueries([]int{0}, 1)
+
+		results := cache.ConcurrentBFSQueries([]int{0, 1}, 0)
+		if !reflect.DeepEqual(results, map[int][]int{0: {0, 1, 2, 3, 4}}) {
+			t.Errorf("Expected only the cached result for start 0, got %v", results)
+		}
+	})
+
+	t.Run("Concurrent batches", func(t *testing.T) {
+		graph := buildSampleGraph()
+		cache := NewBFSCache(graph)
+		queries := []int{0, 1, 2, 3, 4, 5}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				results := cache.ConcurrentBFSQueries(queries, 3)
+				for _, start := range queries {
+					if !reflect.DeepEqual(results[start], bfsReference(graph, start)) {
+						t.Errorf("Wrong result for start %d: %v", start, results[start])
+					}
+				}
+			})
+		}
+		wg.Wait()
+	})
+}
+
+func TestBFSCacheReachable(t *testing.T) {
+	// Components {0, 1, 2}, {3, 4} and {5}, with 2 -> 3 and 5 -> 4.
+	graph := map[int][]int{
+		0: {1},
+		1: {2},
+		2: {0, 3},
+		3: {4},
+		4: {3},
+		5: {4},
+	}
+	testCases := []struct {
+		start int
+		want  []int
+	}{
+		{start: 1, want: []int{0, 1, 2, 3, 4}},
+		{start: 0, want: []int{0, 1, 2, 3, 4}},
+		{start: 2, want: []int{0, 1, 2, 3, 4}},
+		{start: 4, want: []int{3, 4}},
+		{start: 3, want: []int{3, 4}},
+		{start: 5, want: []int{3, 4, 5}},
+		{start: 9, want: []int{9}},
+	}
+
+	cache := NewBFSCache(graph)
+	for _, tc := range testCases {
+		got := cache.Reachable(tc.start)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("For start %d, expected %v, got %v", tc.start, tc.want, got)
+		}
+	}
+	// Overlapping starts of a component share its traversal.
+	if len(cache.reachable) != 3 {
+		t.Errorf("Expected 3 traversed components, got %d", len(cache.reachable))
+	}
+
+	if !cache.Connected(5, 3) || cache.Connected(3, 5) || cache.Connected(0, 5) {
+		t.Error("Expected 3 to be reachable from 5 only")
+	}
+}
+
+func TestStrongComponents(t *testing.T) {
+	graph := buildRandomGraph(300, 600, 42)
+	components := strongComponents(graph)
+	nodes := graphNodes(graph)
+	if len(components) != len(nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(nodes), len(components))
+	}
+
+	reachable := make(map[int]map[int]bool, len(nodes))
+	for _, u := range nodes {
+		reachable[u] = make(map[int]bool)
+		for _, v := range bfsReference(graph, u) {
+			reachable[u][v] = true
+		}
+	}
+	for _, u := range nodes {
+		for _, v := range nodes {
+			strong := reachable[u][v] && reachable[v][u]
+			if same := components[u] == components[v]; same != strong {
+				t.Fatalf("Nodes %d and %d: expected same component %t, got %t", u, v, strong, same)
+			}
+		}
+	}
+}
+
+// buildLargeCycleGraph returns a cycle 0 -> 1 -> ... -> size-1 -> 0, a single
+// strongly connected component.
+func buildLargeCycleGraph(size int) map[int][]int {
+	graph := buildLargeLinearGraph(size)
+	graph[size-1] = []int{0}
+	return graph
+}
+
+func BenchmarkReachableUncached(b *testing.B) {
+	graph := buildLargeCycleGraph(10000)
+
+	for b.Loop() {
+		for start := range 200 {
+			bfs(graph, start)
+		}
+	}
+}
+
+func BenchmarkBFSCacheReachableOverlapping(b *testing.B) {
+	graph := buildLargeCycleGraph(10000)
+
+	for b.Loop() {
+		cache := NewBFSCache(graph)
+		for start := range 200 {
+			cache.Reachable(start)
+		}
+	}
+}
+
+// repeatedQueries returns n queries cycling through the first distinct nodes.
+func repeatedQueries(n, distinct int) []int {
+	queries := make([]int, n)
+	for i := range queries {
+		queries[i] = i % distinct
+	}
+	return queries
+}
+
+func BenchmarkConcurrentBFSQueriesRepeated(b *testing.B) {
+	graph := buildLargeLinearGraph(10000)
+	queries := repeatedQueries(200, 10)
+
+	for b.Loop() {
+		ConcurrentBFSQueries(graph, queries, 4)
+	}
+}
+
+func BenchmarkBFSCacheColdRepeated(b *testing.B) {
+	graph := buildLargeLinearGraph(10000)
+	queries := repeatedQueries(200, 10)
+
+	for b.Loop() {
+		NewBFSCache(graph).ConcurrentBFSQueries(queries, 4)
+	}
+}
+
+func BenchmarkBFSCacheWarmRepeated(b *testing.B) {
+	graph := buildLargeLinearGraph(10000)
+	queries := repeatedQueries(200, 10)
+	cache := NewBFSCache(graph)
+	cache.ConcurrentBFSQueries(queries, 4)
+
+	for b.Loop() {
+		cache.ConcurrentBFSQueries(queries, 4)
+	}
+}
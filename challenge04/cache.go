@@ -0,0 +1,214 @@
+package challenge04
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// BFSCache answers BFS queries on a fixed graph, and remembers every traversal
+// so that repeated starts, within a batch or across batches, are served from
+// memory. BFS order depends on the starting node, so traversals are cached per
+// start. Reachability does not: every node of a strongly connected component
+// reaches the same nodes, so Reachable and Connected serve overlapping starts
+// in a component from the first traversal of that component. The graph must
+// not be modified once the cache is created.
+//
+// The cache is unbounded, and safe for concurrent use. Concurrent batches that
+// miss on the same start may each compute it once.
+type BFSCache struct {
+	graph   map[int][]int
+	mu      sync.RWMutex
+	results map[int][]int
+
+	// components maps every node to its strongly connected component, and is
+	// computed on the first reachability query.
+	componentsOnce sync.Once
+	components     map[int]int
+	// reachable holds the sorted nodes reachable from each traversed component.
+	reachable map[int][]int
+}
+
+// NewBFSCache creates an empty cache for the given graph.
+func NewBFSCache(graph map[int][]int) *BFSCache {
+	return &BFSCache{
+		graph:     graph,
+		results:   make(map[int][]int),
+		reachable: make(map[int][]int),
+	}
+}
+
+// ConcurrentBFSQueries is like the package-level ConcurrentBFSQueries, but only
+// traverses starts that are not already cached, each at most once. The returned
+// slices are shared with the cache, and must not be modified.
+func (c *BFSCache) ConcurrentBFSQueries(queries []int, numWorkers int) map[int][]int {
+	res := make(map[int][]int, len(queries))
+	var misses []int
+
+	c.mu.RLock()
+	for _, q := range queries {
+		if _, ok := res[q]; ok {
+			continue
+		}
+		if order, ok := c.results[q]; ok {
+			res[q] = order
+			continue
+		}
+		// Mark as seen, so that duplicates are only traversed once.
+		res[q] = nil
+		misses = append(misses, q)
+	}
+	c.mu.RUnlock()
+
+	computed, _ := fanOut(
+		context.Background(),
+		misses,
+		numWorkers,
+		func(_ context.Context, start int) ([]int, error) {
+			return bfs(c.graph, start), nil
+		},
+	)
+
+	c.mu.Lock()
+	for q, order := range computed {
+		c.results[q] = order
+	}
+	c.mu.Unlock()
+
+	for _, q := range misses {
+		if order, ok := computed[q]; ok {
+			res[q] = order
+		} else {
+			delete(res, q)
+		}
+	}
+
+	return res
+}
+
+// Len returns the number of cached traversals.
+func (c *BFSCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.results)
+}
+
+// Reachable returns the nodes reachable from start, start included, in
+// increasing order. Only the first start of each strongly connected component
+// is traversed. The returned slice is shared with the cache, and must not be
+// modified.
+func (c *BFSCache) Reachable(start int) []int {
+	c.componentsOnce.Do(func() {
+		c.components = strongComponents(c.graph)
+	})
+	component, ok := c.components[start]
+	if !ok {
+		// Nodes outside the graph only reach themselves, as in bfs.
+		return []int{start}
+	}
+
+	c.mu.RLock()
+	nodes, ok := c.reachable[component]
+	c.mu.RUnlock()
+	if ok {
+		return nodes
+	}
+
+	nodes = bfs(c.graph, start)
+	slices.Sort(nodes)
+	c.mu.Lock()
+	c.reachable[component] = nodes
+	c.mu.Unlock()
+	return nodes
+}
+
+// Connected reports whether to is reachable from from, as Reachable does.
+func (c *BFSCache) Connected(from, to int) bool {
+	_, found := slices.BinarySearch(c.Reachable(from), to)
+	return found
+}
+
+// strongComponents numbers the strongly connected components of the graph with
+// an iterative Tarjan's algorithm, and returns the component of every node,
+// including nodes that only appear as neighbors.
+func strongComponents(graph map[int][]int) map[int]int {
+	t := &tarjan{
+		graph:      graph,
+		index:      make(map[int]int),
+		low:        make(map[int]int),
+		onStack:    make(map[int]bool),
+		components: make(map[int]int),
+	}
+	for _, root := range graphNodes(graph) {
+		if _, ok := t.index[root]; !ok {
+			t.search(root)
+		}
+	}
+	return t.components
+}
+
+// tarjan holds the state of strongComponents.
+type tarjan struct {
+	graph         map[int][]int
+	index         map[int]int
+	low           map[int]int
+	onStack       map[int]bool
+	stack         []int
+	components    map[int]int
+	numComponents int
+}
+
+// search runs the depth-first search from root, with an explicit call stack
+// so that long paths cannot overflow the goroutine stack.
+func (t *tarjan) search(root int) {
+	type frame struct {
+		node, next int
+	}
+	t.visit(root)
+	calls := []frame{{node: root}}
+	for len(calls) > 0 {
+		f := &calls[len(calls)-1]
+		if neighbors := t.graph[f.node]; f.next < len(neighbors) {
+			v := neighbors[f.next]
+			f.next++
+			if _, ok := t.index[v]; !ok {
+				t.visit(v)
+				calls = append(calls, frame{node: v})
+			} else if t.onStack[v] {
+				t.low[f.node] = min(t.low[f.node], t.index[v])
+			}
+			continue
+		}
+
+		u := f.node
+		calls = calls[:len(calls)-1]
+		if len(calls) > 0 {
+			parent := calls[len(calls)-1].node
+			t.low[parent] = min(t.low[parent], t.low[u])
+		}
+		if t.low[u] == t.index[u] {
+			t.popComponent(u)
+		}
+	}
+}
+
+func (t *tarjan) visit(u int) {
+	t.index[u], t.low[u] = len(t.index), len(t.index)
+	t.stack = append(t.stack, u)
+	t.onStack[u] = true
+}
+
+// popComponent assigns the nodes above root on the stack, root included, to a
+// new component.
+func (t *tarjan) popComponent(root int) {
+	for {
+		w := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[w] = false
+		t.components[w] = t.numComponents
+		if w == root {
+			break
+		}
+	}
+	t.numComponents++
+}
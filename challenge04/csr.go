@@ -0,0 +1,134 @@
+package challenge04
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// CSRGraph is a directed graph in compressed sparse row form, over the dense
+// node IDs 0 to NumNodes()-1. The neighbors of u are stored contiguously in
+// targets[offsets[u]:offsets[u+1]], which takes far less memory than an
+// adjacency map and is faster to traverse.
+type CSRGraph struct {
+	offsets []int
+	targets []int32
+
+	// reverse is the transpose of the graph, built on first use by ParallelBFS.
+	reverse     *CSRGraph
+	reverseOnce sync.Once
+}
+
+// NewCSRGraph builds a CSRGraph from an adjacency list, keeping the order of each
+// node's neighbors, so that BFS visits nodes in the same order on both.
+// Nodes that only appear as neighbors, and IDs missing in between, become nodes
+// without outgoing edges. It returns an error if any node ID is negative or
+// does not fit in an int32.
+func NewCSRGraph(graph map[int][]int) (*CSRGraph, error) {
+	numNodes, numEdges := 0, 0
+	for u, neighbors := range graph {
+		if err := checkCSRNode(u); err != nil {
+			return nil, err
+		}
+		numNodes = max(numNodes, u+1)
+		for _, v := range neighbors {
+			if err := checkCSRNode(v); err != nil {
+				return nil, err
+			}
+			numNodes = max(numNodes, v+1)
+		}
+		numEdges += len(neighbors)
+	}
+
+	g := &CSRGraph{
+		offsets: make([]int, numNodes+1),
+		targets: make([]int32, 0, numEdges),
+	}
+	for u := range numNodes {
+		for _, v := range graph[u] {
+			g.targets = append(g.targets, int32(v)) //nolint:gosec // G115: range checked above
+		}
+		g.offsets[u+1] = len(g.targets)
+	}
+
+	return g, nil
+}
+
+func checkCSRNode(u int) error {
+	if u < 0 || u >= math.MaxInt32 {
+		return fmt.Errorf("node %d out of range [0, %d)", u, math.MaxInt32)
+	}
+	return nil
+}
+
+// NumNodes returns the number of nodes.
+func (g *CSRGraph) NumNodes() int {
+	return len(g.offsets) - 1
+}
+
+// NumEdges returns the number of edges.
+func (g *CSRGraph) NumEdges() int {
+	return len(g.targets)
+}
+
+// Neighbors returns the neighbors of u, or nil if u is not a node. The returned
+// slice is shared with the graph, and must not be modified.
+func (g *CSRGraph) Neighbors(u int) []int32 {
+	if u < 0 || u >= g.NumNodes() {
+		return nil
+	}
+	return g.targets[g.offsets[u]:g.offsets[u+1]]
+}
+
+// ConcurrentBFSQueries is like the package-level ConcurrentBFSQueries, but
+// traverses the CSR graph, tracking visited nodes in a bitset.
+func (g *CSRGraph) ConcurrentBFSQueries(queries []int, numWorkers int) map[int][]int {
+	res, _ := fanOut(
+		context.Background(),
+		queries,
+		numWorkers,
+		func(_ context.Context, start int) ([]int, error) {
+			return g.bfs(start), nil
+		},
+	)
+	return res
+}
+
+func (g *CSRGraph) bfs(start int) []int {
+	if start < 0 || start >= g.NumNodes() {
+		// Like a node that is missing from an adjacency map.
+		return []int{start}
+	}
+
+	visited := newBitset(g.NumNodes())
+	visited.set(start)
+
+	// Nodes are visited in the order they are enqueued, so the result doubles as the queue.
+	order := []int{start}
+	for head := 0; head < len(order); head++ {
+		for _, v := range g.Neighbors(order[head]) {
+			if !visited.has(int(v)) {
+				visited.set(int(v))
+				order = append(order, int(v))
+			}
+		}
+	}
+
+	return order
+}
+
+// bitset is a fixed-size set of non-negative integers.
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<(i%64)) != 0
+}
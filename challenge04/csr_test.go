@@ -0,0 +1,145 @@
+package challenge04
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func buildRandomGraph(numNodes, numEdges int, seed int64) map[int][]int {
+	rng := rand.New(rand.NewSource(seed))
+	graph := make(map[int][]int, numNodes)
+	for range numEdges {
+		u, v := rng.Intn(numNodes), rng.Intn(numNodes)
+		graph[u] = append(graph[u], v)
+	}
+	return graph
+}
+
+func TestNewCSRGraph(t *testing.T) {
+	t.Run("Sample graph", func(t *testing.T) {
+		g, err := NewCSRGraph(buildSampleGraph())
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if g.NumNodes() != 6 {
+			t.Errorf("Expected 6 nodes, got %d", g.NumNodes())
+		}
+		if g.NumEdges() != 7 {
+			t.Errorf("Expected 7 edges, got %d", g.NumEdges())
+		}
+		if !reflect.DeepEqual(g.Neighbors(1), []int32{2, 3}) {
+			t.Errorf("Expected neighbors [2 3] for node 1, got %v", g.Neighbors(1))
+		}
+		if g.Neighbors(4) == nil || len(g.Neighbors(4)) != 0 {
+			t.Errorf("Expected no neighbors for node 4, got %v", g.Neighbors(4))
+		}
+		if g.Neighbors(6) != nil || g.Neighbors(-1) != nil {
+			t.Errorf("Expected nil neighbors for nodes outside the graph")
+		}
+	})
+
+	t.Run("Nodes only reachable as neighbors", func(t *testing.T) {
+		g, err := NewCSRGraph(map[int][]int{0: {3}})
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if g.NumNodes() != 4 {
+			t.Errorf("Expected 4 nodes, got %d", g.NumNodes())
+		}
+	})
+
+	t.Run("Empty graph", func(t *testing.T) {
+		g, err := NewCSRGraph(map[int][]int{})
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if g.NumNodes() != 0 || g.NumEdges() != 0 {
+			t.Errorf("Expected empty graph, got %d nodes and %d edges", g.NumNodes(), g.NumEdges())
+		}
+	})
+
+	t.Run("Invalid node IDs", func(t *testing.T) {
+		for _, graph := range []map[int][]int{
+			{-1: {0}},
+			{0: {-1}},
+			{0: {math.MaxInt32}},
+		} {
+			if _, err := NewCSRGraph(graph); err == nil {
+				t.Errorf("Expected error for graph %v", graph)
+			}
+		}
+	})
+}
+
+func TestCSRGraphConcurrentBFSQueries(t *testing.T) {
+	testCases := []struct {
+		name    string
+		graph   map[int][]int
+		queries []int
+	}{
+		{
+			name:    "Sample graph",
+			graph:   buildSampleGraph(),
+			queries: []int{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name:    "Self-loops",
+			graph:   map[int][]int{0: {0, 1}, 1: {}},
+			queries: []int{0, 1},
+		},
+		{
+			name:    "Start outside the graph",
+			graph:   buildSampleGraph(),
+			queries: []int{10, -1},
+		},
+		{
+			name:    "Random graph",
+			graph:   buildRandomGraph(1000, 3000, 1),
+			queries: []int{0, 10, 100, 999},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := NewCSRGraph(tc.graph)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+
+			results := g.ConcurrentBFSQueries(tc.queries, 3)
+
+			if len(results) != len(tc.queries) {
+				t.Fatalf("Expected %d results, got %d", len(tc.queries), len(results))
+			}
+			for _, start := range tc.queries {
+				refOrder := bfsReference(tc.graph, start)
+				if !reflect.DeepEqual(results[start], refOrder) {
+					t.Errorf("For start %d, expected %v, got %v", start, refOrder, results[start])
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBFSQueriesMap(b *testing.B) {
+	graph := buildRandomGraph(100000, 1000000, 1)
+	queries := []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+	for b.Loop() {
+		ConcurrentBFSQueries(graph, queries, 4)
+	}
+}
+
+func BenchmarkBFSQueriesCSR(b *testing.B) {
+	g, err := NewCSRGraph(buildRandomGraph(100000, 1000000, 1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	queries := []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+	for b.Loop() {
+		g.ConcurrentBFSQueries(queries, 4)
+	}
+}
@@ -0,0 +1,387 @@
+package challenge04
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxLineSize is the longest line the text loaders accept.
+const maxLineSize = 1024 * 1024
+
+// ParseError reports a malformed graph file, and the line on which it was found.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// LoadEdgeList reads a graph with one "u v" edge per line. Blank lines and
+// lines starting with '#' are ignored. If directed is false, every edge is
+// added in both directions.
+func LoadEdgeList(r io.Reader, directed bool) (map[int][]int, error) {
+	b := newGraphBuilder(directed)
+	err := scanLines(r, func(lineNo int, fields []string) error {
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			return nil
+		}
+		if len(fields) != 2 {
+			return &ParseError{
+				Line: lineNo,
+				Msg:  fmt.Sprintf("expected 2 fields, got %d", len(fields)),
+			}
+		}
+		nodes, err := parseNodes(lineNo, fields)
+		if err != nil {
+			return err
+		}
+		b.addEdge(nodes[0], nodes[1])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.graph, nil
+}
+
+// LoadDIMACS reads a graph in the DIMACS format: "c" comment lines, a single
+// "p <problem> <nodes> <edges>" line, then "a u v w" arc lines (directed, as in
+// shortest-path instances) or "e u v" edge lines (undirected, as in clique
+// instances). Nodes are numbered from 1 to <nodes>, and arcs without a weight,
+// as well as edges, get a weight of 1. Only nodes with an edge are present in
+// the result, so that the declared counts do not decide what is allocated.
+func LoadDIMACS(r io.Reader) (WeightedGraph, error) {
+	d := dimacsParser{graph: make(WeightedGraph)}
+	if err := scanLines(r, d.parseLine); err != nil {
+		return nil, err
+	}
+	if d.problemLine == 0 {
+		return nil, &ParseError{Line: d.lastLine, Msg: `missing "p" line`}
+	}
+	if d.numEdges != d.wantEdges {
+		return nil, &ParseError{
+			Line: d.problemLine,
+			Msg:  fmt.Sprintf("declared %d edges, found %d", d.wantEdges, d.numEdges),
+		}
+	}
+	return d.graph, nil
+}
+
+type dimacsParser struct {
+	graph       WeightedGraph
+	problemLine int
+	lastLine    int
+	numNodes    int
+	wantEdges   int
+	numEdges    int
+}
+
+func (d *dimacsParser) parseLine(lineNo int, fields []string) error {
+	d.lastLine = lineNo
+	if len(fields) == 0 || fields[0] == "c" {
+		return nil
+	}
+
+	switch fields[0] {
+	case "p":
+		return d.parseProblem(lineNo, fields)
+	case "a", "e":
+		return d.parseEdge(lineNo, fields)
+	default:
+		return &ParseError{Line: lineNo, Msg: fmt.Sprintf("unknown line type %q", fields[0])}
+	}
+}
+
+func (d *dimacsParser) parseProblem(lineNo int, fields []string) error {
+	if d.problemLine != 0 {
+		return &ParseError{
+			Line: lineNo,
+			Msg:  fmt.Sprintf(`duplicate "p" line, first on line %d`, d.problemLine),
+		}
+	}
+	if len(fields) != 4 {
+		return &ParseError{Line: lineNo, Msg: `expected "p <problem> <nodes> <edges>"`}
+	}
+	counts, err := parseNodes(lineNo, fields[2:])
+	if err != nil {
+		return err
+	}
+	if counts[0] < 0 || counts[1] < 0 {
+		return &ParseError{
+			Line: lineNo,
+			Msg:  fmt.Sprintf("negative count in %q", strings.Join(fields[2:], " ")),
+		}
+	}
+	d.problemLine, d.numNodes, d.wantEdges = lineNo, counts[0], counts[1]
+	return nil
+}
+
+func (d *dimacsParser) parseEdge(lineNo int, fields []string) error {
+	if d.problemLine == 0 {
+		return &ParseError{Line: lineNo, Msg: `edge before "p" line`}
+	}
+	arc := fields[0] == "a"
+	if len(fields) != 3 && (!arc || len(fields) != 4) {
+		return &ParseError{Line: lineNo, Msg: fmt.Sprintf("unexpected %d fields", len(fields))}
+	}
+
+	nodes, err := parseNodes(lineNo, fields[1:3])
+	if err != nil {
+		return err
+	}
+	for _, u := range nodes {
+		if u < 1 || u > d.numNodes {
+			return &ParseError{
+				Line: lineNo,
+				Msg:  fmt.Sprintf("node %d out of range [1, %d]", u, d.numNodes),
+			}
+		}
+	}
+
+	weight := 1.0
+	if len(fields) == 4 {
+		weight, err = strconv.ParseFloat(fields[3], 64)
+		if err != nil || math.Signbit(weight) || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return &ParseError{Line: lineNo, Msg: fmt.Sprintf("invalid weight %q", fields[3])}
+		}
+	}
+
+	u, v := nodes[0], nodes[1]
+	if _, ok := d.graph[v]; !ok {
+		d.graph[v] = []Edge{}
+	}
+	d.graph[u] = append(d.graph[u], Edge{To: v, Weight: weight})
+	if !arc && u != v {
+		d.graph[v] = append(d.graph[v], Edge{To: u, Weight: weight})
+	}
+	d.numEdges++
+	return nil
+}
+
+// LoadJSON reads a graph in the JSON adjacency format:
+//
+//	{
+//	  "directed": false,
+//	  "adjacency": {"0": [1, 2], "1": [2], "2": []}
+//	}
+//
+// The "adjacency" object is required, and the "directed" flag defaults to true.
+// If it is false, every edge is added in both directions, so listing an edge on
+// either or both of its ends is the same. Unknown fields are ignored, but not
+// data after the document.
+func LoadJSON(r io.Reader) (map[int][]int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := jsonParser{data: data, dec: json.NewDecoder(bytes.NewReader(data)), directed: true}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	b := newGraphBuilder(p.directed)
+	for _, entry := range p.adjacency {
+		b.addNode(entry.node)
+		for _, v := range entry.neighbors {
+			b.addEdge(entry.node, v)
+		}
+	}
+	return b.graph, nil
+}
+
+type jsonParser struct {
+	data         []byte
+	dec          *json.Decoder
+	directed     bool
+	hasAdjacency bool
+	adjacency    []adjacencyEntry
+}
+
+type adjacencyEntry struct {
+	node      int
+	neighbors []int
+}
+
+// parse walks the document token by token, rather than unmarshalling it at
+// once, so that errors can point to the line being read.
+func (p *jsonParser) parse() error {
+	if err := p.expectDelim('{'); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "directed":
+			err = p.decode(&p.directed, "directed flag")
+		case "adjacency":
+			p.hasAdjacency = true
+			err = p.parseAdjacency()
+		default:
+			err = p.decode(&json.RawMessage{}, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := p.expectDelim('}'); err != nil {
+		return err
+	}
+	if !p.hasAdjacency {
+		return p.errorf("missing adjacency")
+	}
+	if _, err := p.dec.Token(); !errors.Is(err, io.EOF) {
+		// Point to the data, not to the end of the document.
+		rest := p.data[p.dec.InputOffset():]
+		offset := len(p.data) - len(bytes.TrimLeft(rest, " \t\r\n"))
+		return &ParseError{
+			Line: lineAt(p.data, offset),
+			Msg:  "unexpected data after the document",
+		}
+	}
+	return nil
+}
+
+func (p *jsonParser) parseAdjacency() error {
+	if err := p.expectDelim('{'); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		u, err := strconv.Atoi(key)
+		if err != nil {
+			return p.errorf("invalid node %q", key)
+		}
+		var neighbors []int
+		if err := p.decode(&neighbors, "neighbors of node "+key); err != nil {
+			return err
+		}
+		p.adjacency = append(p.adjacency, adjacencyEntry{node: u, neighbors: neighbors})
+	}
+	return p.expectDelim('}')
+}
+
+func (p *jsonParser) key() (string, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return "", p.errorf("%v", err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", p.errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+func (p *jsonParser) decode(v any, what string) error {
+	if err := p.dec.Decode(v); err != nil {
+		return p.errorf("invalid %s: %v", what, err)
+	}
+	return nil
+}
+
+func (p *jsonParser) expectDelim(want json.Delim) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+	if tok != want {
+		return p.errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+func (p *jsonParser) errorf(format string, args ...any) error {
+	return &ParseError{
+		Line: lineAt(p.data, int(p.dec.InputOffset())),
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// lineAt returns the 1-based line number of the byte at offset in data.
+func lineAt(data []byte, offset int) int {
+	offset = min(max(offset, 0), len(data))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// scanLines calls parse with the 1-based number and whitespace-separated fields
+// of every line read from r, and stops at the first error.
+func scanLines(r io.Reader, parse func(lineNo int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if err := parse(lineNo, strings.Fields(scanner.Text())); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %w", lineNo+1, err)
+	}
+	return nil
+}
+
+func parseNodes(lineNo int, fields []string) ([]int, error) {
+	nodes := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Msg: fmt.Sprintf("invalid integer %q", f)}
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+// graphBuilder accumulates edges into an adjacency list. Undirected edges are
+// added in both directions, skipping reverse edges that are already present.
+type graphBuilder struct {
+	graph    map[int][]int
+	directed bool
+	edges    map[[2]int]struct{}
+}
+
+func newGraphBuilder(directed bool) *graphBuilder {
+	return &graphBuilder{
+		graph:    make(map[int][]int),
+		directed: directed,
+		edges:    make(map[[2]int]struct{}),
+	}
+}
+
+func (b *graphBuilder) addNode(u int) {
+	if _, ok := b.graph[u]; !ok {
+		b.graph[u] = []int{}
+	}
+}
+
+func (b *graphBuilder) addEdge(u, v int) {
+	b.addNode(u)
+	b.addNode(v)
+	if b.directed {
+		b.graph[u] = append(b.graph[u], v)
+		return
+	}
+	for _, e := range [][2]int{{u, v}, {v, u}} {
+		if _, ok := b.edges[e]; !ok {
+			b.edges[e] = struct{}{}
+			b.graph[e[0]] = append(b.graph[e[0]], e[1])
+		}
+	}
+}
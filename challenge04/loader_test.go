@@ -0,0 +1,210 @@
+package challenge04
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assertParseErrorLine(t *testing.T, err error, wantLine int) {
+	t.Helper()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected ParseError but got %T: %v", err, err)
+	}
+	if parseErr.Line != wantLine {
+		t.Errorf("Expected error on line %d, got %v", wantLine, err)
+	}
+}
+
+func TestLoadEdgeList(t *testing.T) {
+	input := `# sample graph
+0 1
+0 2
+
+1	2
+3 3
+`
+
+	t.Run("Directed", func(t *testing.T) {
+		graph, err := LoadEdgeList(strings.NewReader(input), true)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := map[int][]int{0: {1, 2}, 1: {2}, 2: {}, 3: {3}}
+		if !reflect.DeepEqual(graph, expected) {
+			t.Errorf("Expected %v, got %v", expected, graph)
+		}
+	})
+
+	t.Run("Undirected", func(t *testing.T) {
+		graph, err := LoadEdgeList(strings.NewReader(input+"2 1\n"), false)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := map[int][]int{0: {1, 2}, 1: {0, 2}, 2: {0, 1}, 3: {3}}
+		if !reflect.DeepEqual(graph, expected) {
+			t.Errorf("Expected %v, got %v", expected, graph)
+		}
+	})
+
+	t.Run("Invalid lines", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			input string
+			line  int
+		}{
+			{name: "Too few fields", input: "0 1\n2\n", line: 2},
+			{name: "Too many fields", input: "0 1 2\n", line: 1},
+			{name: "Not an integer", input: "# c\n\n0 x\n", line: 3},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := LoadEdgeList(strings.NewReader(tc.input), true)
+				assertParseErrorLine(t, err, tc.line)
+			})
+		}
+	})
+}
+
+func TestLoadDIMACS(t *testing.T) {
+	t.Run("Shortest-path instance", func(t *testing.T) {
+		input := `c sample
+p sp 4 3
+a 1 2 7
+a 2 3 1.5
+a 1 3 10
+`
+		graph, err := LoadDIMACS(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := WeightedGraph{
+			1: {{To: 2, Weight: 7}, {To: 3, Weight: 10}},
+			2: {{To: 3, Weight: 1.5}},
+			3: {},
+		}
+		if !reflect.DeepEqual(graph, expected) {
+			t.Errorf("Expected %v, got %v", expected, graph)
+		}
+
+		adjacency := map[int][]int{1: {2, 3}, 2: {3}, 3: {}}
+		if !reflect.DeepEqual(graph.Adjacency(), adjacency) {
+			t.Errorf("Expected adjacency %v, got %v", adjacency, graph.Adjacency())
+		}
+	})
+
+	t.Run("Large declared node count", func(t *testing.T) {
+		graph, err := LoadDIMACS(strings.NewReader("p sp 3000000000 1\na 1 2999999999 1\n"))
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if len(graph) != 2 {
+			t.Errorf("Expected 2 nodes, got %d", len(graph))
+		}
+	})
+
+	t.Run("Clique instance", func(t *testing.T) {
+		graph, err := LoadDIMACS(strings.NewReader("p edge 3 2\ne 1 2\ne 2 3\n"))
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := map[int][]int{1: {2}, 2: {1, 3}, 3: {2}}
+		if !reflect.DeepEqual(graph.Adjacency(), expected) {
+			t.Errorf("Expected %v, got %v", expected, graph.Adjacency())
+		}
+	})
+
+	t.Run("Invalid lines", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			input string
+			line  int
+		}{
+			{name: "Edge before problem", input: "c x\na 1 2 3\np sp 2 1\n", line: 2},
+			{name: "Duplicate problem", input: "p sp 2 0\np sp 2 0\n", line: 2},
+			{name: "Malformed problem", input: "p sp 2\n", line: 1},
+			{name: "Negative node count", input: "c x\np sp -1 0\n", line: 2},
+			{name: "Negative edge count", input: "p sp 2 -1\n", line: 1},
+			{name: "Node out of range", input: "p sp 2 1\na 1 3 1\n", line: 2},
+			{name: "Negative weight", input: "p sp 2 1\na 1 2 -1\n", line: 2},
+			{name: "Negative zero weight", input: "p sp 2 1\na 1 2 -0\n", line: 2},
+			{name: "NaN weight", input: "p sp 2 1\na 1 2 NaN\n", line: 2},
+			{name: "Infinite weight", input: "p sp 2 1\na 1 2 +Inf\n", line: 2},
+			{name: "Weighted edge", input: "p edge 2 1\ne 1 2 5\n", line: 2},
+			{name: "Unknown line type", input: "p sp 2 0\nx 1 2\n", line: 2},
+			{name: "Edge count mismatch", input: "c x\np sp 2 2\na 1 2 1\n", line: 2},
+			{name: "Missing problem", input: "c x\nc y\n", line: 2},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := LoadDIMACS(strings.NewReader(tc.input))
+				assertParseErrorLine(t, err, tc.line)
+			})
+		}
+	})
+}
+
+func TestLoadJSON(t *testing.T) {
+	t.Run("Directed by default", func(t *testing.T) {
+		graph, err := LoadJSON(strings.NewReader(`{"adjacency": {"0": [1, 2], "1": [2]}}`))
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := map[int][]int{0: {1, 2}, 1: {2}, 2: {}}
+		if !reflect.DeepEqual(graph, expected) {
+			t.Errorf("Expected %v, got %v", expected, graph)
+		}
+	})
+
+	t.Run("Undirected", func(t *testing.T) {
+		input := `{
+  "name": "triangle",
+  "adjacency": {"0": [1, 2], "1": [0, 2], "2": []},
+  "directed": false
+}`
+		graph, err := LoadJSON(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := map[int][]int{0: {1, 2}, 1: {0, 2}, 2: {0, 1}}
+		if !reflect.DeepEqual(graph, expected) {
+			t.Errorf("Expected %v, got %v", expected, graph)
+		}
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			input string
+			line  int
+		}{
+			{name: "Not an object", input: "[]", line: 1},
+			{
+				name:  "Invalid node",
+				input: "{\n\"adjacency\": {\n\"0\": [],\n\"x\": []\n}\n}",
+				line:  4,
+			},
+			{
+				name:  "Invalid neighbors",
+				input: "{\n\"adjacency\": {\n\"0\": [1, \"2\"]\n}\n}",
+				line:  3,
+			},
+			{name: "Invalid flag", input: "{\n\n\"directed\": \"no\"\n}", line: 3},
+			{name: "Truncated", input: "{\n\"adjacency\": {\n\"0\": [1]\n", line: 3},
+			{name: "Missing adjacency", input: "{\n\"nodes\": 3\n}", line: 3},
+			{
+				name:  "Trailing data",
+				input: "{\n\"adjacency\": {}\n}\ngarbage {",
+				line:  4,
+			},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := LoadJSON(strings.NewReader(tc.input))
+				assertParseErrorLine(t, err, tc.line)
+			})
+		}
+	})
+}
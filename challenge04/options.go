@@ -0,0 +1,96 @@
+package challenge04
+
+import "context"
+
+// BFSOption restricts the part of the graph a BFS traversal explores.
+type BFSOption func(*bfsOptions)
+
+type bfsOptions struct {
+	// maxDepth is negative when unlimited.
+	maxDepth  int
+	keep      func(node int) bool
+	target    int
+	hasTarget bool
+	// depths records the depths of the visited nodes, for the callers that
+	// return them.
+	depths bool
+}
+
+func newBFSOptions(opts ...BFSOption) bfsOptions {
+	o := bfsOptions{maxDepth: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// withDepths records the depth of every visited node in BFSResult.Depths.
+func withDepths() BFSOption {
+	return func(o *bfsOptions) {
+		o.depths = true
+	}
+}
+
+// WithMaxDepth stops the traversal at nodes that are depth edges away from the
+// start, so that only nodes within depth hops are visited. A negative depth
+// means no limit.
+func WithMaxDepth(depth int) BFSOption {
+	return func(o *bfsOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithNodeFilter prunes the traversal at nodes for which keep returns false:
+// they are neither visited nor expanded. The start node is always visited.
+// keep is called concurrently by the workers.
+func WithNodeFilter(keep func(node int) bool) BFSOption {
+	return func(o *bfsOptions) {
+		o.keep = keep
+	}
+}
+
+// WithTarget stops the traversal as soon as target is visited, so that the
+// result ends with target if it is reachable.
+func WithTarget(target int) BFSOption {
+	return func(o *bfsOptions) {
+		o.target = target
+		o.hasTarget = true
+	}
+}
+
+// ConcurrentBFSQueriesWithOptions is like ConcurrentBFSQueries, but every
+// traversal is restricted by opts, which makes questions such as "which nodes
+// are within k hops" or "is there a path avoiding these nodes" cheap to answer.
+func ConcurrentBFSQueriesWithOptions(
+	graph map[int][]int,
+	queries []int,
+	numWorkers int,
+	opts ...BFSOption,
+) map[int][]int {
+	o := newBFSOptions(opts...)
+	res, _ := fanOut(
+		context.Background(),
+		queries,
+		numWorkers,
+		func(ctx context.Context, start int) ([]int, error) {
+			result, err := bfsContext(ctx, graph, start, o)
+			return result.Order, err
+		},
+	)
+	return res
+}
+
+// expand reports whether the neighbors of a node at the given depth are explored.
+func (o bfsOptions) expand(depth int) bool {
+	return o.maxDepth < 0 || depth < o.maxDepth
+}
+
+// visit reports whether a discovered node is visited.
+func (o bfsOptions) visit(node int) bool {
+	return o.keep == nil || o.keep(node)
+}
+
+// done reports whether the traversal stops after visiting node.
+func (o bfsOptions) done(node int) bool {
+	return o.hasTarget && node == o.target
+}
@@ -0,0 +1,129 @@
+package challenge04
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrentBFSQueriesWithOptions(t *testing.T) {
+	graph := buildSampleGraph()
+
+	testCases := []struct {
+		name     string
+		queries  []int
+		opts     []BFSOption
+		expected map[int][]int
+	}{
+		{
+			name:     "No options",
+			queries:  []int{0, 5},
+			expected: map[int][]int{0: {0, 1, 2, 3, 4}, 5: {5, 2, 3, 4}},
+		},
+		{
+			name:     "Max depth",
+			queries:  []int{0, 5},
+			opts:     []BFSOption{WithMaxDepth(1)},
+			expected: map[int][]int{0: {0, 1, 2}, 5: {5, 2}},
+		},
+		{
+			name:     "Max depth zero",
+			queries:  []int{0},
+			opts:     []BFSOption{WithMaxDepth(0)},
+			expected: map[int][]int{0: {0}},
+		},
+		{
+			name:     "Negative max depth is unlimited",
+			queries:  []int{0},
+			opts:     []BFSOption{WithMaxDepth(-1)},
+			expected: map[int][]int{0: {0, 1, 2, 3, 4}},
+		},
+		{
+			name:     "Node filter",
+			queries:  []int{0},
+			opts:     []BFSOption{WithNodeFilter(func(node int) bool { return node != 2 })},
+			expected: map[int][]int{0: {0, 1, 3, 4}},
+		},
+		{
+			name:     "Node filter does not apply to start",
+			queries:  []int{2},
+			opts:     []BFSOption{WithNodeFilter(func(node int) bool { return node != 2 })},
+			expected: map[int][]int{2: {2, 3, 4}},
+		},
+		{
+			name:     "Target",
+			queries:  []int{0, 4},
+			opts:     []BFSOption{WithTarget(2)},
+			expected: map[int][]int{0: {0, 1, 2}, 4: {4}},
+		},
+		{
+			name:    "Combined options",
+			queries: []int{0},
+			opts: []BFSOption{
+				WithMaxDepth(2),
+				WithNodeFilter(func(node int) bool { return node != 1 }),
+				WithTarget(4),
+			},
+			expected: map[int][]int{0: {0, 2, 3}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ConcurrentBFSQueriesWithOptions(graph, tc.queries, 2, tc.opts...)
+			if !reflect.DeepEqual(res, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestNodeFilterCalledOncePerNode(t *testing.T) {
+	var calls atomic.Int64
+	keep := func(node int) bool {
+		calls.Add(1)
+		return node != 2
+	}
+	// Node 2 is a neighbor of both 0 and 1, but is only filtered once.
+	graph := map[int][]int{0: {1, 2}, 1: {2}}
+	res := ConcurrentBFSQueriesWithOptions(graph, []int{0}, 1, WithNodeFilter(keep))
+	if !reflect.DeepEqual(res[0], []int{0, 1}) {
+		t.Errorf("Expected [0 1], got %v", res[0])
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 filter calls, got %d", calls.Load())
+	}
+}
+
+func TestBFSDepths(t *testing.T) {
+	graph := buildSampleGraph()
+	ctx := context.Background()
+
+	plain, _ := bfsContext(ctx, graph, 0, newBFSOptions(WithMaxDepth(1)))
+	if plain.Depths != nil {
+		t.Errorf("Expected no depths unless asked for, got %v", plain.Depths)
+	}
+	withDepth, _ := bfsContext(ctx, graph, 0, newBFSOptions(WithMaxDepth(1), withDepths()))
+	if !reflect.DeepEqual(withDepth.Order, plain.Order) {
+		t.Errorf("Expected order %v, got %v", plain.Order, withDepth.Order)
+	}
+	if want := []int{0, 1, 1}; !reflect.DeepEqual(withDepth.Depths, want) {
+		t.Errorf("Expected depths %v, got %v", want, withDepth.Depths)
+	}
+
+	// Pruned nodes do not count towards the levels.
+	keep := WithNodeFilter(func(node int) bool { return node != 1 })
+	pruned, _ := bfsContext(ctx, graph, 0, newBFSOptions(keep, withDepths()))
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(pruned.Depths, want) {
+		t.Errorf("Expected depths %v of %v, got %v", want, pruned.Order, pruned.Depths)
+	}
+}
+
+func BenchmarkBFSWithinHops(b *testing.B) {
+	graph := buildLargeLinearGraph(100000)
+	queries := []int{0, 1000, 50000}
+	for b.Loop() {
+		ConcurrentBFSQueriesWithOptions(graph, queries, 4, WithMaxDepth(10))
+	}
+}
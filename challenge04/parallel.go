@@ -0,0 +1,186 @@
+package challenge04
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Direction-switching thresholds from Beamer et al., "Direction-Optimizing
+// Breadth-First Search" (SC 2012).
+const (
+	// Go bottom-up once the frontier has more than 1/alpha of the unexplored edges.
+	bottomUpAlpha = 14
+	// Go back top-down once the frontier has fewer than 1/beta of the nodes.
+	topDownBeta = 24
+)
+
+// directionPolicy decides whether the next BFS level is expanded bottom-up.
+type directionPolicy func(bottomUp bool, frontierNodes, frontierEdges, unexploredEdges, numNodes int) bool
+
+// ParallelBFS runs a single BFS from start, splitting each level across
+// numWorkers goroutines. Small frontiers are expanded top-down, by claiming the
+// unvisited neighbors of frontier nodes. Large frontiers are expanded bottom-up,
+// by having every unvisited node look for a parent in the frontier, which skips
+// most edges once the frontier covers a large part of the graph.
+//
+// The result has the same levels as a sequential BFS, but since the nodes of a
+// level are discovered concurrently, they are listed in increasing order of ID.
+// It returns ctx.Err() if ctx is done before the traversal completes.
+func (g *CSRGraph) ParallelBFS(ctx context.Context, start, numWorkers int) (BFSResult, error) {
+	return g.levelSynchronousBFS(ctx, start, numWorkers, directionOptimizing)
+}
+
+func directionOptimizing(
+	bottomUp bool,
+	frontierNodes, frontierEdges, unexploredEdges, numNodes int,
+) bool {
+	if bottomUp {
+		return frontierNodes >= numNodes/topDownBeta
+	}
+	return frontierEdges > unexploredEdges/bottomUpAlpha
+}
+
+func (g *CSRGraph) levelSynchronousBFS(
+	ctx context.Context,
+	start, numWorkers int,
+	policy directionPolicy,
+) (BFSResult, error) {
+	result := BFSResult{Start: start, Order: []int{start}, Depths: []int{0}}
+	n := g.NumNodes()
+	if start < 0 || start >= n {
+		// Like a node that is missing from an adjacency map.
+		return result, nil
+	}
+	numWorkers = max(numWorkers, 1)
+
+	// depths[v] is -1 until v is visited. Workers claim nodes with atomic operations.
+	depths := make([]atomic.Int32, n)
+	for i := range depths {
+		depths[i].Store(-1)
+	}
+	depths[start].Store(0)
+
+	frontier := []int32{int32(start)} //nolint:gosec // G115: start < n <= math.MaxInt32
+	unexploredEdges := g.NumEdges()
+	bottomUp := false
+
+	for level := int32(0); len(frontier) > 0; level++ {
+		if err := ctx.Err(); err != nil {
+			return BFSResult{}, err
+		}
+
+		frontierEdges := 0
+		for _, u := range frontier {
+			frontierEdges += len(g.Neighbors(int(u)))
+		}
+		bottomUp = policy(bottomUp, len(frontier), frontierEdges, unexploredEdges, n)
+		unexploredEdges -= frontierEdges
+
+		if bottomUp {
+			frontier = g.bottomUpStep(depths, level, numWorkers)
+		} else {
+			frontier = g.topDownStep(frontier, depths, level, numWorkers)
+		}
+
+		slices.Sort(frontier)
+		for _, v := range frontier {
+			result.Order = append(result.Order, int(v))
+			result.Depths = append(result.Depths, int(level+1))
+		}
+	}
+
+	return result, nil
+}
+
+// topDownStep returns the unvisited neighbors of the frontier nodes, and marks
+// them as visited at the next level.
+func (g *CSRGraph) topDownStep(
+	frontier []int32,
+	depths []atomic.Int32,
+	level int32,
+	numWorkers int,
+) []int32 {
+	return parallelChunks(len(frontier), numWorkers, func(lo, hi int) []int32 {
+		var next []int32
+		for _, u := range frontier[lo:hi] {
+			for _, v := range g.Neighbors(int(u)) {
+				if depths[v].CompareAndSwap(-1, level+1) {
+					next = append(next, v)
+				}
+			}
+		}
+		return next
+	})
+}
+
+// bottomUpStep returns the unvisited nodes that have a parent in the frontier,
+// and marks them as visited at the next level. Every node is only written by
+// the worker that owns it, and a node's search stops at the first parent found.
+func (g *CSRGraph) bottomUpStep(depths []atomic.Int32, level int32, numWorkers int) []int32 {
+	reverse := g.transpose()
+	return parallelChunks(g.NumNodes(), numWorkers, func(lo, hi int) []int32 {
+		var next []int32
+		for v := lo; v < hi; v++ {
+			if depths[v].Load() != -1 {
+				continue
+			}
+			for _, u := range reverse.Neighbors(v) {
+				if depths[u].Load() == level {
+					depths[v].Store(level + 1)
+					next = append(next, int32(v)) //nolint:gosec // G115: v < n <= math.MaxInt32
+					break
+				}
+			}
+		}
+		return next
+	})
+}
+
+// transpose returns the graph with every edge reversed, building it on first use.
+func (g *CSRGraph) transpose() *CSRGraph {
+	g.reverseOnce.Do(func() {
+		n := g.NumNodes()
+		reverse := &CSRGraph{
+			offsets: make([]int, n+1),
+			targets: make([]int32, g.NumEdges()),
+		}
+		// Count the in-degrees, then turn them into offsets.
+		for _, v := range g.targets {
+			reverse.offsets[v+1]++
+		}
+		for v := range n {
+			reverse.offsets[v+1] += reverse.offsets[v]
+		}
+		next := slices.Clone(reverse.offsets[:n])
+		for u := range n {
+			for _, v := range g.Neighbors(u) {
+				reverse.targets[next[v]] = int32(u)
+				next[v]++
+			}
+		}
+		g.reverse = reverse
+	})
+	return g.reverse
+}
+
+// parallelChunks splits [0, n) into numWorkers contiguous chunks, runs work on
+// each chunk in its own goroutine, and concatenates the results in chunk order.
+func parallelChunks(n, numWorkers int, work func(lo, hi int) []int32) []int32 {
+	numChunks := min(numWorkers, n)
+	if numChunks <= 1 {
+		return work(0, n)
+	}
+
+	parts := make([][]int32, numChunks)
+	var wg sync.WaitGroup
+	for i := range numChunks {
+		wg.Go(func() {
+			parts[i] = work(i*n/numChunks, (i+1)*n/numChunks)
+		})
+	}
+	wg.Wait()
+
+	return slices.Concat(parts...)
+}
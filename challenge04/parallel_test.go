@@ -0,0 +1,136 @@
+package challenge04
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// levelsReference returns the sequential BFS levels from start, each sorted by node ID.
+func levelsReference(graph map[int][]int, start int) [][]int {
+	result, _ := bfsContext(context.Background(), graph, start, newBFSOptions(withDepths()))
+	levels := result.Levels()
+	for _, level := range levels {
+		slices.Sort(level)
+	}
+	return levels
+}
+
+func alwaysTopDown(bool, int, int, int, int) bool { return false }
+
+func alwaysBottomUp(bool, int, int, int, int) bool { return true }
+
+func TestParallelBFS(t *testing.T) {
+	testCases := []struct {
+		name  string
+		graph map[int][]int
+		start int
+	}{
+		{name: "Sample graph", graph: buildSampleGraph(), start: 0},
+		{name: "Tree", graph: map[int][]int{0: {2, 1}, 1: {4, 3}, 2: {6, 5}}, start: 0},
+		{name: "Linear graph", graph: buildLargeLinearGraph(1000), start: 10},
+		{name: "Star graph", graph: buildStarGraph(0, 5000), start: 0},
+		{name: "Sparse random graph", graph: buildRandomGraph(5000, 10000, 2), start: 0},
+		{name: "Dense random graph", graph: buildRandomGraph(2000, 100000, 3), start: 7},
+	}
+
+	policies := []struct {
+		name   string
+		policy directionPolicy
+	}{
+		{name: "direction-optimizing", policy: directionOptimizing},
+		{name: "top-down", policy: alwaysTopDown},
+		{name: "bottom-up", policy: alwaysBottomUp},
+	}
+
+	for _, tc := range testCases {
+		g, err := NewCSRGraph(tc.graph)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		expected := levelsReference(tc.graph, tc.start)
+
+		for _, p := range policies {
+			for _, numWorkers := range []int{1, 4} {
+				t.Run(tc.name+"/"+p.name, func(t *testing.T) {
+					result, err := g.levelSynchronousBFS(
+						context.Background(),
+						tc.start,
+						numWorkers,
+						p.policy,
+					)
+					if err != nil {
+						t.Fatalf("Did not expect error but got: %v", err)
+					}
+					if result.Start != tc.start || result.Order[0] != tc.start {
+						t.Errorf("Expected traversal to start at %d, got %v", tc.start, result)
+					}
+					if levels := result.Levels(); !reflect.DeepEqual(levels, expected) {
+						t.Errorf("Expected levels %v, got %v", expected, levels)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestParallelBFSEdgeCases(t *testing.T) {
+	g, err := NewCSRGraph(buildSampleGraph())
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	t.Run("Start outside the graph", func(t *testing.T) {
+		result, err := g.ParallelBFS(context.Background(), 42, 2)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if !reflect.DeepEqual(result.Order, []int{42}) {
+			t.Errorf("Expected [42], got %v", result.Order)
+		}
+	})
+
+	t.Run("Zero workers", func(t *testing.T) {
+		result, err := g.ParallelBFS(context.Background(), 0, 0)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if !reflect.DeepEqual(result.Order, []int{0, 1, 2, 3, 4}) {
+			t.Errorf("Expected [0 1 2 3 4], got %v", result.Order)
+		}
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := g.ParallelBFS(ctx, 0, 2); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func BenchmarkSingleQuerySequential(b *testing.B) {
+	g, err := NewCSRGraph(buildRandomGraph(1000000, 10000000, 1))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		g.bfs(0)
+	}
+}
+
+func BenchmarkSingleQueryParallel(b *testing.B) {
+	g, err := NewCSRGraph(buildRandomGraph(1000000, 10000000, 1))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		if _, err := g.ParallelBFS(context.Background(), 0, 8); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package challenge04
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+)
+
+// Default limits of a BFS server, used for zero ServerConfig fields.
+const (
+	defaultMaxNodes        = 1_000_000
+	defaultMaxEdges        = 10_000_000
+	defaultMaxBatchQueries = 1000
+	defaultMaxBodyBytes    = 1 << 20
+)
+
+// ServerConfig configures a BFS server. Zero fields take the defaults.
+type ServerConfig struct {
+	// NumWorkers is the size of the worker pool for batch queries.
+	// Defaults to runtime.NumCPU().
+	NumWorkers int
+	// MaxNodes and MaxEdges bound the size of the graph the server accepts.
+	MaxNodes int
+	MaxEdges int
+	// MaxBatchQueries bounds the number of starting nodes in a batch query.
+	MaxBatchQueries int
+	// MaxBodyBytes bounds the size of a batch query request body.
+	MaxBodyBytes int64
+}
+
+// BFSResponse is the result of a BFS query.
+type BFSResponse struct {
+	Start  int   `json:"start"`
+	Order  []int `json:"order"`
+	Depths []int `json:"depths"`
+}
+
+// BatchBFSRequest is the body of a batch BFS query.
+type BatchBFSRequest struct {
+	Starts   []int `json:"starts"`
+	MaxDepth *int  `json:"maxDepth"`
+}
+
+// BatchBFSResponse lists the results of a batch BFS query, one per distinct
+// starting node, in request order.
+type BatchBFSResponse struct {
+	Results []BFSResponse `json:"results"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type bfsServer struct {
+	graph  map[int][]int
+	config ServerConfig
+}
+
+// SetupServer configures the HTTP routes of a service that answers BFS queries
+// on graph, which must not be modified afterwards:
+//   - GET /bfs?start=<node>[&maxDepth=<depth>] runs a single query.
+//   - POST /bfs takes a BatchBFSRequest, and runs its queries on the worker pool.
+//
+// It returns an error if the graph exceeds the configured limits.
+func SetupServer(graph map[int][]int, config ServerConfig) (http.Handler, error) {
+	config = config.withDefaults()
+
+	numNodes, numEdges := len(graphNodes(graph)), 0
+	for _, neighbors := range graph {
+		numEdges += len(neighbors)
+	}
+	if numNodes > config.MaxNodes {
+		return nil, fmt.Errorf("graph has %d nodes, limit is %d", numNodes, config.MaxNodes)
+	}
+	if numEdges > config.MaxEdges {
+		return nil, fmt.Errorf("graph has %d edges, limit is %d", numEdges, config.MaxEdges)
+	}
+
+	s := &bfsServer{graph: graph, config: config}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /bfs", s.getBFS)
+	mux.HandleFunc("POST /bfs", s.postBFS)
+	return mux, nil
+}
+
+func (c ServerConfig) withDefaults() ServerConfig {
+	if c.NumWorkers <= 0 {
+		c.NumWorkers = runtime.NumCPU()
+	}
+	if c.MaxNodes <= 0 {
+		c.MaxNodes = defaultMaxNodes
+	}
+	if c.MaxEdges <= 0 {
+		c.MaxEdges = defaultMaxEdges
+	}
+	if c.MaxBatchQueries <= 0 {
+		c.MaxBatchQueries = defaultMaxBatchQueries
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	return c
+}
+
+func (s *bfsServer) getBFS(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, err := strconv.Atoi(q.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "start must be an integer")
+		return
+	}
+	maxDepth := -1
+	if v := q.Get("maxDepth"); v != "" {
+		maxDepth, err = strconv.Atoi(v)
+		if err != nil || maxDepth < 0 {
+			writeError(w, http.StatusBadRequest, "maxDepth must be a non-negative integer")
+			return
+		}
+	}
+
+	result, err := bfsContext(
+		r.Context(),
+		s.graph,
+		start,
+		newBFSOptions(WithMaxDepth(maxDepth), withDepths()),
+	)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, BFSResponse(result))
+}
+
+func (s *bfsServer) postBFS(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+	var req BatchBFSRequest
+	body := http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Starts) == 0 {
+		writeError(w, http.StatusBadRequest, "starts must not be empty")
+		return
+	}
+	if len(req.Starts) > s.config.MaxBatchQueries {
+		writeError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("at most %d starts per batch", s.config.MaxBatchQueries),
+		)
+		return
+	}
+	maxDepth := -1
+	if req.MaxDepth != nil {
+		if *req.MaxDepth < 0 {
+			writeError(w, http.StatusBadRequest, "maxDepth must be a non-negative integer")
+			return
+		}
+		maxDepth = *req.MaxDepth
+	}
+
+	opts := newBFSOptions(WithMaxDepth(maxDepth), withDepths())
+	results, unprocessed := fanOut(
+		r.Context(),
+		req.Starts,
+		s.config.NumWorkers,
+		func(ctx context.Context, start int) (BFSResult, error) {
+			return bfsContext(ctx, s.graph, start, opts)
+		},
+	)
+	if len(unprocessed) > 0 {
+		err := &IncompleteQueriesError{Unprocessed: unprocessed, cause: r.Context().Err()}
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	resp := BatchBFSResponse{Results: make([]BFSResponse, 0, len(results))}
+	seen := make(map[int]struct{}, len(results))
+	for _, start := range req.Starts {
+		if _, ok := seen[start]; !ok {
+			seen[start] = struct{}{}
+			resp.Results = append(resp.Results, BFSResponse(results[start]))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
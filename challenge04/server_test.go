@@ -0,0 +1,165 @@
+package challenge04
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupTestServer(t *testing.T, config ServerConfig) http.Handler {
+	t.Helper()
+	server, err := SetupServer(buildSampleGraph(), config)
+	if err != nil {
+		t.Fatalf("Failed to set up server: %v", err)
+	}
+	return server
+}
+
+func serve(server http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSetupServerLimits(t *testing.T) {
+	if _, err := SetupServer(buildSampleGraph(), ServerConfig{MaxNodes: 5}); err == nil {
+		t.Error("Expected error for graph with too many nodes")
+	}
+	if _, err := SetupServer(buildSampleGraph(), ServerConfig{MaxEdges: 6}); err == nil {
+		t.Error("Expected error for graph with too many edges")
+	}
+	config := ServerConfig{MaxNodes: 6, MaxEdges: 7}
+	if _, err := SetupServer(buildSampleGraph(), config); err != nil {
+		t.Errorf("Did not expect error but got: %v", err)
+	}
+}
+
+func TestGetBFS(t *testing.T) {
+	server := setupTestServer(t, ServerConfig{})
+
+	testCases := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       BFSResponse
+	}{
+		{
+			name:       "Full traversal",
+			url:        "/bfs?start=0",
+			wantStatus: http.StatusOK,
+			want: BFSResponse{
+				Start:  0,
+				Order:  []int{0, 1, 2, 3, 4},
+				Depths: []int{0, 1, 1, 2, 3},
+			},
+		},
+		{
+			name:       "Max depth",
+			url:        "/bfs?start=0&maxDepth=1",
+			wantStatus: http.StatusOK,
+			want:       BFSResponse{Start: 0, Order: []int{0, 1, 2}, Depths: []int{0, 1, 1}},
+		},
+		{
+			name:       "Max depth zero",
+			url:        "/bfs?start=5&maxDepth=0",
+			wantStatus: http.StatusOK,
+			want:       BFSResponse{Start: 5, Order: []int{5}, Depths: []int{0}},
+		},
+		{name: "Missing start", url: "/bfs", wantStatus: http.StatusBadRequest},
+		{name: "Invalid start", url: "/bfs?start=x", wantStatus: http.StatusBadRequest},
+		{
+			name:       "Negative max depth",
+			url:        "/bfs?start=0&maxDepth=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(server, http.MethodGet, tc.url, "")
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var got BFSResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPostBFS(t *testing.T) {
+	server := setupTestServer(t, ServerConfig{NumWorkers: 2, MaxBatchQueries: 3, MaxBodyBytes: 100})
+
+	t.Run("Batch", func(t *testing.T) {
+		rr := serve(server, http.MethodPost, "/bfs", `{"starts": [5, 1, 5], "maxDepth": 1}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var got BatchBFSResponse
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		want := BatchBFSResponse{Results: []BFSResponse{
+			{Start: 5, Order: []int{5, 2}, Depths: []int{0, 1}},
+			{Start: 1, Order: []int{1, 2, 3}, Depths: []int{0, 1, 1}},
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	})
+
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "Invalid body", body: `{"starts": "0"}`, wantStatus: http.StatusBadRequest},
+		{name: "No starts", body: `{"starts": []}`, wantStatus: http.StatusBadRequest},
+		{
+			name:       "Too many starts",
+			body:       `{"starts": [0, 1, 2, 3]}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "Negative max depth",
+			body:       `{"starts": [0], "maxDepth": -2}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Body too large",
+			body:       `{"starts": [0], "padding": "` + strings.Repeat("x", 100) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(server, http.MethodPost, "/bfs", tc.body)
+			if rr.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body)
+			}
+			var got ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || got.Error == "" {
+				t.Errorf("Expected an error body, got %q", rr.Body)
+			}
+		})
+	}
+
+	t.Run("Method not allowed", func(t *testing.T) {
+		rr := serve(server, http.MethodDelete, "/bfs", "")
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+		}
+	})
+}
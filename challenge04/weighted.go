@@ -0,0 +1,191 @@
+package challenge04
+
+import (
+	"container/heap"
+	"context"
+	"slices"
+)
+
+// Edge is a directed edge to node To with a non-negative Weight.
+type Edge struct {
+	To     int
+	Weight float64
+}
+
+// WeightedGraph is an adjacency list with edge weights, e.g., graph[u] = []Edge{{To: v, Weight: w}}
+type WeightedGraph map[int][]Edge
+
+// Adjacency returns the graph without its edge weights, in the format taken by
+// ConcurrentBFSQueries.
+func (g WeightedGraph) Adjacency() map[int][]int {
+	graph := make(map[int][]int, len(g))
+	for u, edges := range g {
+		neighbors := make([]int, len(edges))
+		for i, e := range edges {
+			neighbors[i] = e.To
+		}
+		graph[u] = neighbors
+	}
+	return graph
+}
+
+// Heuristic estimates the cost of the cheapest path from node to goal.
+// For A* to return shortest paths, it must never overestimate that cost,
+// and h(u) <= w(u, v) + h(v) must hold for every edge (u, v).
+type Heuristic func(node, goal int) float64
+
+// PathQuery is a single-pair shortest-path query from Start to Goal.
+type PathQuery struct {
+	Start int
+	Goal  int
+}
+
+// ShortestPaths holds the result of a shortest-path search from Start.
+// Dist maps every settled node to its distance from Start, and Prev maps every
+// settled node other than Start to its predecessor on a shortest path.
+type ShortestPaths struct {
+	Start int
+	Dist  map[int]float64
+	Prev  map[int]int
+}
+
+// PathTo reconstructs the shortest path from Start to node by walking the predecessor tree.
+// It returns nil if node was not reached.
+func (sp ShortestPaths) PathTo(node int) []int {
+	if _, ok := sp.Dist[node]; !ok {
+		return nil
+	}
+	path := []int{node}
+	for node != sp.Start {
+		node = sp.Prev[node]
+		path = append(path, node)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// ConcurrentDijkstraQueries concurrently computes single-source shortest paths
+// from every starting node in queries, using numWorkers goroutines.
+// Edge weights must be non-negative.
+func ConcurrentDijkstraQueries(
+	graph WeightedGraph,
+	queries []int,
+	numWorkers int,
+) map[int]ShortestPaths {
+	res, _ := fanOut(
+		context.Background(),
+		queries,
+		numWorkers,
+		func(_ context.Context, start int) (ShortestPaths, error) {
+			return dijkstra(graph, start), nil
+		},
+	)
+	return res
+}
+
+// ConcurrentAStarQueries concurrently computes shortest paths for every query
+// using A* guided by h, and numWorkers goroutines. A search stops as soon as its
+// goal is settled, so the returned Dist and Prev cover only the explored part of
+// the graph. Edge weights must be non-negative.
+func ConcurrentAStarQueries(
+	graph WeightedGraph,
+	queries []PathQuery,
+	h Heuristic,
+	numWorkers int,
+) map[PathQuery]ShortestPaths {
+	res, _ := fanOut(
+		context.Background(),
+		queries,
+		numWorkers,
+		func(_ context.Context, q PathQuery) (ShortestPaths, error) {
+			return aStar(graph, q, h), nil
+		},
+	)
+	return res
+}
+
+func dijkstra(graph WeightedGraph, start int) ShortestPaths {
+	return search(graph, start, nil, func(int) float64 { return 0 })
+}
+
+func aStar(graph WeightedGraph, q PathQuery, h Heuristic) ShortestPaths {
+	isGoal := func(node int) bool { return node == q.Goal }
+	return search(graph, q.Start, isGoal, func(node int) float64 { return h(node, q.Goal) })
+}
+
+// search is a best-first search ordered by distance + estimate(node).
+// It stops early once a node for which isGoal returns true is settled.
+func search(
+	graph WeightedGraph,
+	start int,
+	isGoal func(int) bool,
+	estimate func(int) float64,
+) ShortestPaths {
+	sp := ShortestPaths{
+		Start: start,
+		Dist:  make(map[int]float64),
+		Prev:  make(map[int]int),
+	}
+	// Tentative distances of discovered nodes, settled or not.
+	best := map[int]float64{start: 0}
+	pq := &priorityQueue{{node: start, priority: estimate(start)}}
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(pqItem)
+		if _, settled := sp.Dist[item.node]; settled {
+			// Stale entry; the node was already reached by a shorter path.
+			continue
+		}
+		d := best[item.node]
+		sp.Dist[item.node] = d
+		if isGoal != nil && isGoal(item.node) {
+			break
+		}
+
+		for _, e := range graph[item.node] {
+			if _, settled := sp.Dist[e.To]; settled {
+				continue
+			}
+			nd := d + e.Weight
+			if old, ok := best[e.To]; ok && old <= nd {
+				continue
+			}
+			best[e.To] = nd
+			sp.Prev[e.To] = item.node
+			heap.Push(pq, pqItem{node: e.To, priority: nd + estimate(e.To)})
+		}
+	}
+
+	// Drop predecessors of nodes that were discovered but never settled.
+	for node := range sp.Prev {
+		if _, settled := sp.Dist[node]; !settled {
+			delete(sp.Prev, node)
+		}
+	}
+
+	return sp
+}
+
+type pqItem struct {
+	node     int
+	priority float64
+}
+
+// priorityQueue is a min-heap of pqItem ordered by priority.
+type priorityQueue []pqItem
+
+func (pq *priorityQueue) Len() int           { return len(*pq) }
+func (pq *priorityQueue) Less(i, j int) bool { return (*pq)[i].priority < (*pq)[j].priority }
+func (pq *priorityQueue) Swap(i, j int)      { (*pq)[i], (*pq)[j] = (*pq)[j], (*pq)[i] }
+
+func (pq *priorityQueue) Push(x any) {
+	*pq = append(*pq, x.(pqItem))
+}
+
+func (pq *priorityQueue) Pop() any {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	*pq = old[:n-1]
+	return item
+}
@@ -0,0 +1,120 @@
+package challenge04
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func buildSampleWeightedGraph() WeightedGraph {
+	// 0 -1-> 1 -1-> 2 -1-> 3
+	// 0 -------5--------> 3
+	// 0 -4-> 2
+	// 4 (isolated)
+	return WeightedGraph{
+		0: {{To: 1, Weight: 1}, {To: 3, Weight: 5}, {To: 2, Weight: 4}},
+		1: {{To: 2, Weight: 1}},
+		2: {{To: 3, Weight: 1}},
+		3: {},
+		4: {},
+	}
+}
+
+func buildGridGraph(n int) (WeightedGraph, Heuristic) {
+	// n x n grid with unit weights; node id = row*n + col
+	graph := make(WeightedGraph)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			u := r*n + c
+			if c+1 < n {
+				graph[u] = append(graph[u], Edge{To: u + 1, Weight: 1})
+				graph[u+1] = append(graph[u+1], Edge{To: u, Weight: 1})
+			}
+			if r+1 < n {
+				graph[u] = append(graph[u], Edge{To: u + n, Weight: 1})
+				graph[u+n] = append(graph[u+n], Edge{To: u, Weight: 1})
+			}
+		}
+	}
+	manhattan := func(node, goal int) float64 {
+		return math.Abs(float64(node/n-goal/n)) + math.Abs(float64(node%n-goal%n))
+	}
+	return graph, manhattan
+}
+
+func TestConcurrentDijkstraQueries(t *testing.T) {
+	graph := buildSampleWeightedGraph()
+	results := ConcurrentDijkstraQueries(graph, []int{0, 1, 4}, 2)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	wantDist := map[int]map[int]float64{
+		0: {0: 0, 1: 1, 2: 2, 3: 3},
+		1: {1: 0, 2: 1, 3: 2},
+		4: {4: 0},
+	}
+	for start, want := range wantDist {
+		if !reflect.DeepEqual(results[start].Dist, want) {
+			t.Errorf("Start=%d, expected distances %v, got %v", start, want, results[start].Dist)
+		}
+	}
+
+	if path := results[0].PathTo(3); !reflect.DeepEqual(path, []int{0, 1, 2, 3}) {
+		t.Errorf("Expected path [0 1 2 3], got %v", path)
+	}
+	if path := results[0].PathTo(0); !reflect.DeepEqual(path, []int{0}) {
+		t.Errorf("Expected path [0], got %v", path)
+	}
+	if path := results[0].PathTo(4); path != nil {
+		t.Errorf("Expected nil path to unreachable node, got %v", path)
+	}
+}
+
+func TestConcurrentAStarQueries(t *testing.T) {
+	const n = 20
+	graph, h := buildGridGraph(n)
+	queries := []PathQuery{
+		{Start: 0, Goal: n*n - 1},
+		{Start: n - 1, Goal: n * (n - 1)},
+		{Start: 5, Goal: 5},
+	}
+
+	results := ConcurrentAStarQueries(graph, queries, h, 3)
+	reference := ConcurrentDijkstraQueries(graph, []int{0, n - 1, 5}, 3)
+
+	for _, q := range queries {
+		sp := results[q]
+		want := reference[q.Start].Dist[q.Goal]
+		if got, ok := sp.Dist[q.Goal]; !ok || got != want {
+			t.Errorf("Query %v: expected distance %v, got %v", q, want, got)
+		}
+
+		path := sp.PathTo(q.Goal)
+		if len(path) != int(want)+1 || path[0] != q.Start || path[len(path)-1] != q.Goal {
+			t.Errorf("Query %v: invalid path %v", q, path)
+		}
+
+		// A* should settle fewer nodes than a full Dijkstra search.
+		if q.Start != q.Goal && len(sp.Dist) >= len(reference[q.Start].Dist) {
+			t.Errorf("Query %v: A* settled %d nodes, expected fewer than %d",
+				q, len(sp.Dist), len(reference[q.Start].Dist))
+		}
+	}
+}
+
+func TestConcurrentAStarQueriesUnreachableGoal(t *testing.T) {
+	graph := buildSampleWeightedGraph()
+	zero := func(_, _ int) float64 { return 0 }
+	q := PathQuery{Start: 0, Goal: 4}
+
+	results := ConcurrentAStarQueries(graph, []PathQuery{q}, zero, 1)
+
+	if path := results[q].PathTo(4); path != nil {
+		t.Errorf("Expected nil path to unreachable goal, got %v", path)
+	}
+	if len(results[q].Dist) != 4 {
+		t.Errorf("Expected the whole component to be explored, got %v", results[q].Dist)
+	}
+}
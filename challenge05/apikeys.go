@@ -0,0 +1,325 @@
+package challenge05
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultRotationGrace is how long a rotated key keeps working by default.
+const defaultRotationGrace = 24 * time.Hour
+
+// apiKeySecretBytes is the number of random bytes in the secret of an API key.
+const apiKeySecretBytes = 32
+
+// Errors returned by an APIKeyStore.
+var (
+	ErrKeyNotFound  = errors.New("API key not found")
+	ErrKeyNotActive = errors.New("API key is revoked or expired")
+)
+
+// APIKey is an API key as stored. Keys are presented as "<id>.<secret>", and
+// only a hash of the secret is stored: the secret is returned once, when the
+// key is created.
+type APIKey struct {
+	ID        string     `json:"id"                  gorm:"primaryKey"`
+	Principal string     `json:"principal"`
+	Roles     []string   `json:"roles"               gorm:"serializer:json"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	// ReplacedBy is the ID of the key this one was rotated to, if any.
+	ReplacedBy string `json:"replacedBy,omitempty"`
+}
+
+func (k *APIKey) active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateAPIKeyRequest is the body of an API key creation request.
+type CreateAPIKeyRequest struct {
+	Principal string     `json:"principal"`
+	Roles     []string   `json:"roles"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// RotateAPIKeyRequest is the body of an API key rotation request.
+type RotateAPIKeyRequest struct {
+	// GracePeriod is how long the old key keeps working, as a Go duration such
+	// as "1h". Defaults to 24 hours.
+	GracePeriod string `json:"gracePeriod"`
+}
+
+// APIKeyResponse holds a new API key, and the token to present it with.
+type APIKeyResponse struct {
+	Key   APIKey `json:"key"`
+	Token string `json:"token"`
+}
+
+// APIKeyStore manages API keys in a database, and validates them as tokens.
+type APIKeyStore struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewAPIKeyStore returns a store backed by db, migrating its schema as needed.
+func NewAPIKeyStore(db *gorm.DB) (*APIKeyStore, error) {
+	if err := db.AutoMigrate(&APIKey{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return &APIKeyStore{db: db, now: time.Now}, nil
+}
+
+// Create creates a key, and returns it along with its token.
+func (s *APIKeyStore) Create(ctx context.Context, req CreateAPIKeyRequest) (APIKey, string, error) {
+	var key APIKey
+	var token string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		key, token, err = s.create(tx, req)
+		return err
+	})
+	return key, token, err
+}
+
+// hashAPIKeySecret hashes secrets with SHA-256: they are long and random, so
+// unlike passwords they need no slow hash.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// List returns every key, including revoked and expired ones, oldest first.
+func (s *APIKeyStore) List(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	if err := s.db.WithContext(ctx).Order("created_at, id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke revokes the key with the given ID, which stops working immediately.
+// Revoking a revoked key is a no-op.
+func (s *APIKeyStore) Revoke(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		key, err := findAPIKey(tx, id)
+		if err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		return tx.Model(&key).Update("revoked_at", s.now()).Error
+	})
+}
+
+// Rotate replaces the key with the given ID by a new key for the same
+// principal, roles and expiry. The old key keeps working for the grace period,
+// so that clients can switch over.
+func (s *APIKeyStore) Rotate(
+	ctx context.Context,
+	id string,
+	grace time.Duration,
+) (APIKey, string, error) {
+	var newKey APIKey
+	var token string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := findAPIKey(tx, id)
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		if !old.active(now) || old.ReplacedBy != "" {
+			return ErrKeyNotActive
+		}
+		newKey, token, err = s.create(tx, CreateAPIKeyRequest{
+			Principal: old.Principal,
+			Roles:     old.Roles,
+			ExpiresAt: old.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		graceEnd := now.Add(grace)
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(graceEnd) {
+			graceEnd = *old.ExpiresAt
+		}
+		return tx.Model(&old).
+			Updates(map[string]any{"expires_at": graceEnd, "replaced_by": newKey.ID}).
+			Error
+	})
+	return newKey, token, err
+}
+
+func findAPIKey(tx *gorm.DB, id string) (APIKey, error) {
+	var key APIKey
+	if err := tx.First(&key, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return APIKey{}, ErrKeyNotFound
+		}
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+// ValidateToken accepts the tokens of active keys.
+func (s *APIKeyStore) ValidateToken(ctx context.Context, token string) (Principal, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: malformed API key", ErrInvalidToken)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed API key", ErrInvalidToken)
+	}
+	key, err := findAPIKey(s.db.WithContext(ctx), id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Principal{}, ErrInvalidToken
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	hash := hashAPIKeySecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.Hash)) != 1 {
+		return Principal{}, ErrInvalidToken
+	}
+	if !key.active(s.now()) {
+		return Principal{}, fmt.Errorf("%w: key revoked or expired", ErrInvalidToken)
+	}
+	return Principal{ID: key.Principal, Roles: key.Roles}, nil
+}
+
+// CredentialKey returns the ID of the API key that token presents.
+func (s *APIKeyStore) CredentialKey(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok || uuid.Validate(id) != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// RegisterAdminRoutes registers the routes of the API key admin API on rt,
+// requiring every policy:
+//   - POST /admin/keys creates a key from a CreateAPIKeyRequest.
+//   - GET /admin/keys lists the keys.
+//   - DELETE /admin/keys/{id} revokes a key.
+//   - POST /admin/keys/{id}/rotate rotates a key, with an optional
+//     RotateAPIKeyRequest.
+func (s *APIKeyStore) RegisterAdminRoutes(rt *Router, policies ...Policy) {
+	rt.Handle("POST /admin/keys", http.HandlerFunc(s.createKey), policies...)
+	rt.Handle("GET /admin/keys", http.HandlerFunc(s.listKeys), policies...)
+	rt.Handle("DELETE /admin/keys/{id}", http.HandlerFunc(s.revokeKey), policies...)
+	rt.Handle("POST /admin/keys/{id}/rotate", http.HandlerFunc(s.rotateKey), policies...)
+}
+
+func (s *APIKeyStore) create(tx *gorm.DB, req CreateAPIKeyRequest) (APIKey, string, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	key := APIKey{
+		ID:        uuid.New().String(),
+		Principal: req.Principal,
+		Roles:     req.Roles,
+		Hash:      hashAPIKeySecret(encoded),
+		CreatedAt: s.now(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := tx.Create(&key).Error; err != nil {
+		return APIKey{}, "", err
+	}
+	return key, key.ID + "." + encoded, nil
+}
+
+func (s *APIKeyStore) createKey(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Principal == "" {
+		writeError(w, http.StatusBadRequest, "principal is required")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		writeError(w, http.StatusBadRequest, "expiresAt must be in the future")
+		return
+	}
+	key, token, err := s.Create(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, APIKeyResponse{Key: key, Token: token})
+}
+
+func (s *APIKeyStore) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if keys == nil {
+		keys = []APIKey{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (s *APIKeyStore) revokeKey(w http.ResponseWriter, r *http.Request) {
+	if err := s.Revoke(r.Context(), r.PathValue("id")); err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *APIKeyStore) rotateKey(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+	// The body is optional, since every field of the request has a default.
+	var req RotateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	grace := defaultRotationGrace
+	if req.GracePeriod != "" {
+		var err error
+		grace, err = time.ParseDuration(req.GracePeriod)
+		if err != nil || grace < 0 {
+			writeError(w, http.StatusBadRequest, "gracePeriod must be a non-negative duration")
+			return
+		}
+	}
+	key, token, err := s.Rotate(r.Context(), r.PathValue("id"), grace)
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, APIKeyResponse{Key: key, Token: token})
+}
+
+func writeKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrKeyNotActive):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
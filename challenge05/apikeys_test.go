@@ -0,0 +1,364 @@
+package challenge05
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestAPIKeyStore returns a store in a fresh database, whose clock reads *now.
+func newTestAPIKeyStore(t *testing.T, now *time.Time) *APIKeyStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	store, err := NewAPIKeyStore(db)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestAPIKeyStore(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	expiry := now.Add(48 * time.Hour)
+
+	key, token, err := store.Create(t.Context(), CreateAPIKeyRequest{
+		Principal: "svc",
+		Roles:     []string{"reader"},
+		ExpiresAt: &expiry,
+	})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if !strings.HasPrefix(token, key.ID+".") {
+		t.Errorf("Expected token to start with the key ID %q, got %q", key.ID, token)
+	}
+	if strings.Contains(key.Hash, strings.TrimPrefix(token, key.ID+".")) {
+		t.Error("Expected the secret not to be stored")
+	}
+	principal, err := store.ValidateToken(t.Context(), token)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if principal.ID != "svc" || len(principal.Roles) != 1 || principal.Roles[0] != "reader" {
+		t.Errorf("Expected principal svc with role reader, got %+v", principal)
+	}
+
+	for _, bad := range []string{"", key.ID, key.ID + ".guess", "other." + token, token + "x"} {
+		assertInvalid(t, store, bad)
+	}
+
+	now = expiry
+	assertInvalid(t, store, token)
+}
+
+func TestAPIKeyStoreRevoke(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	key, token, err := store.Create(t.Context(), CreateAPIKeyRequest{Principal: "svc"})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	assertValid(t, store, token, "svc")
+
+	if err := store.Revoke(t.Context(), key.ID); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	assertInvalid(t, store, token)
+	if err := store.Revoke(t.Context(), key.ID); err != nil {
+		t.Errorf("Expected revoking twice to succeed, got %v", err)
+	}
+	if err := store.Revoke(t.Context(), "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if _, _, err := store.Rotate(t.Context(), key.ID, time.Hour); !errors.Is(err, ErrKeyNotActive) {
+		t.Errorf("Expected ErrKeyNotActive when rotating a revoked key, got %v", err)
+	}
+}
+
+func TestAPIKeyStoreRotate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	old, oldToken, err := store.Create(t.Context(), CreateAPIKeyRequest{Principal: "svc"})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	key, token, err := store.Rotate(t.Context(), old.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if key.ID == old.ID || key.Principal != "svc" {
+		t.Errorf("Expected a new key for svc, got %+v", key)
+	}
+	if _, _, err := store.Rotate(t.Context(), old.ID, time.Hour); !errors.Is(err, ErrKeyNotActive) {
+		t.Errorf("Expected ErrKeyNotActive when rotating a rotated key, got %v", err)
+	}
+
+	// Both keys work during the grace period.
+	now = now.Add(59 * time.Minute)
+	assertValid(t, store, oldToken, "svc")
+	assertValid(t, store, token, "svc")
+
+	now = now.Add(time.Minute)
+	assertInvalid(t, store, oldToken)
+	assertValid(t, store, token, "svc")
+
+	keys, err := store.List(t.Context())
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+	for _, k := range keys {
+		if k.ID == old.ID && k.ReplacedBy != key.ID {
+			t.Errorf("Expected the old key to be replaced by %s, got %q", key.ID, k.ReplacedBy)
+		}
+	}
+}
+
+func TestAPIKeyStoreRotateKeepsEarlierExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	expiry := now.Add(time.Minute)
+	old, oldToken, err := store.Create(t.Context(), CreateAPIKeyRequest{
+		Principal: "svc",
+		ExpiresAt: &expiry,
+	})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	key, _, err := store.Rotate(t.Context(), old.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if key.ExpiresAt == nil || !key.ExpiresAt.Equal(expiry) {
+		t.Errorf("Expected the new key to expire at %v, got %v", expiry, key.ExpiresAt)
+	}
+	now = expiry
+	assertInvalid(t, store, oldToken)
+}
+
+func doAdminRequest(
+	t *testing.T,
+	handler http.Handler,
+	method, url, token string,
+	body any,
+	wantStatus int,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, url, &buf)
+	req.Header.Set("X-Auth-Token", token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, wantStatus, rr.Code, rr.Body)
+	}
+	return rr
+}
+
+func TestAPIKeyAdminAPI(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	_, adminToken, err := store.Create(t.Context(), CreateAPIKeyRequest{
+		Principal: "root",
+		Roles:     []string{"admin"},
+	})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	handler := SetupServerWithAPIKeys(store)
+
+	// Create
+	expiry := now.Add(24 * time.Hour)
+	rr := doAdminRequest(t, handler, http.MethodPost, "/admin/keys", adminToken,
+		CreateAPIKeyRequest{Principal: "svc", ExpiresAt: &expiry}, http.StatusCreated)
+	var created APIKeyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if created.Key.Principal != "svc" || created.Token == "" {
+		t.Fatalf("Expected a key for svc and its token, got %+v", created)
+	}
+	doAdminRequest(t, handler, http.MethodGet, "/secure", created.Token, nil, http.StatusOK)
+	doAdminRequest(t, handler, http.MethodGet, "/secure", validToken, nil, http.StatusOK)
+
+	// List
+	rr = doAdminRequest(t, handler, http.MethodGet, "/admin/keys", adminToken, nil, http.StatusOK)
+	if strings.Contains(rr.Body.String(), "hash") {
+		t.Errorf("Expected the list not to include hashes, got %s", rr.Body)
+	}
+	var keys []APIKey
+	if err := json.NewDecoder(rr.Body).Decode(&keys); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(keys))
+	}
+
+	// Rotate
+	rr = doAdminRequest(t, handler, http.MethodPost, "/admin/keys/"+created.Key.ID+"/rotate",
+		adminToken, RotateAPIKeyRequest{GracePeriod: "10m"}, http.StatusCreated)
+	var rotated APIKeyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&rotated); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	doAdminRequest(t, handler, http.MethodGet, "/secure", created.Token, nil, http.StatusOK)
+	doAdminRequest(t, handler, http.MethodGet, "/secure", rotated.Token, nil, http.StatusOK)
+	now = now.Add(10 * time.Minute)
+	doAdminRequest(
+		t,
+		handler,
+		http.MethodGet,
+		"/secure",
+		created.Token,
+		nil,
+		http.StatusUnauthorized,
+	)
+
+	// Revoke
+	doAdminRequest(t, handler, http.MethodDelete, "/admin/keys/"+rotated.Key.ID,
+		adminToken, nil, http.StatusNoContent)
+	doAdminRequest(
+		t,
+		handler,
+		http.MethodGet,
+		"/secure",
+		rotated.Token,
+		nil,
+		http.StatusUnauthorized,
+	)
+
+	// Rotate without a body, with the default grace period
+	other, otherToken, err := store.Create(t.Context(), CreateAPIKeyRequest{Principal: "svc"})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	doAdminRequest(t, handler, http.MethodPost, "/admin/keys/"+other.ID+"/rotate",
+		adminToken, nil, http.StatusCreated)
+	now = now.Add(23 * time.Hour)
+	doAdminRequest(t, handler, http.MethodGet, "/secure", otherToken, nil, http.StatusOK)
+	now = now.Add(time.Hour)
+	doAdminRequest(t, handler, http.MethodGet, "/secure", otherToken, nil, http.StatusUnauthorized)
+}
+
+func TestAPIKeyAdminAPIErrors(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := newTestAPIKeyStore(t, &now)
+	_, adminToken, err := store.Create(t.Context(), CreateAPIKeyRequest{
+		Principal: "root",
+		Roles:     []string{"admin"},
+	})
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	handler := SetupServerWithAPIKeys(store)
+	past := now.Add(-time.Minute)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		token      string
+		body       any
+		wantStatus int
+	}{
+		{
+			name:       "Unauthenticated",
+			method:     http.MethodGet,
+			url:        "/admin/keys",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Not an admin",
+			method:     http.MethodGet,
+			url:        "/admin/keys",
+			token:      validToken,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Missing principal",
+			method:     http.MethodPost,
+			url:        "/admin/keys",
+			token:      adminToken,
+			body:       CreateAPIKeyRequest{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Expiry in the past",
+			method:     http.MethodPost,
+			url:        "/admin/keys",
+			token:      adminToken,
+			body:       CreateAPIKeyRequest{Principal: "svc", ExpiresAt: &past},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Revoke unknown key",
+			method:     http.MethodDelete,
+			url:        "/admin/keys/missing",
+			token:      adminToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid grace period",
+			method:     http.MethodPost,
+			url:        "/admin/keys/missing/rotate",
+			token:      adminToken,
+			body:       RotateAPIKeyRequest{GracePeriod: "soon"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Rotate unknown key",
+			method:     http.MethodPost,
+			url:        "/admin/keys/missing/rotate",
+			token:      adminToken,
+			body:       RotateAPIKeyRequest{},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Rotate unknown key without a body",
+			method:     http.MethodPost,
+			url:        "/admin/keys/missing/rotate",
+			token:      adminToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Rotate with a malformed body",
+			method:     http.MethodPost,
+			url:        "/admin/keys/missing/rotate",
+			token:      adminToken,
+			body:       json.RawMessage(`"soon"`),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doAdminRequest(t, handler, tc.method, tc.url, tc.token, tc.body, tc.wantStatus)
+		})
+	}
+}
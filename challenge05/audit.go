@@ -0,0 +1,198 @@
+package challenge05
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const auditKey contextKey = "audit"
+
+// Decision is the outcome of authenticating and authorizing a request.
+type Decision string
+
+// Decisions recorded in audit events.
+const (
+	DecisionAllow Decision = "allow"
+	DecisionDeny  Decision = "deny"
+)
+
+// Attribute keys of audit events.
+const (
+	auditMessage      = "auth decision"
+	auditKeyPrincipal = "principal"
+	auditKeyMethod    = "method"
+	auditKeyRoute     = "route"
+	auditKeyDecision  = "decision"
+	auditKeyReason    = "reason"
+	auditKeyLatency   = "latency"
+)
+
+// AuditEvent records the access decision on one request.
+type AuditEvent struct {
+	Time time.Time
+	// Principal is the ID of the authenticated caller, if any.
+	Principal string
+	Method    string
+	// Route is the pattern of the Router route that handled the request, or
+	// its path if none did.
+	Route    string
+	Decision Decision
+	Reason   string
+	// Latency is the time spent handling the request.
+	Latency time.Duration
+}
+
+// Auditor emits an audit event for every request it sees, as a log/slog record
+// with message "auth decision" sent to its sink: allowed requests at level
+// Info, and denied requests at level Warn.
+type Auditor struct {
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewAuditor returns an Auditor that sends its events to sink, such as a
+// handler of an AuditFile, a MemoryAuditSink or any other slog.Handler.
+func NewAuditor(sink slog.Handler) *Auditor {
+	return &Auditor{logger: slog.New(sink), now: time.Now}
+}
+
+// auditEntry collects the decision on a request from the middlewares it goes
+// through; the innermost decision wins.
+type auditEntry struct {
+	route     string
+	principal string
+	decision  Decision
+	reason    string
+}
+
+// Middleware returns a middleware that audits the requests to next. It must
+// come before the authentication and authorization middlewares, which report
+// their decisions to it. Requests that no such middleware decides on are
+// recorded as allowed without authentication.
+func (a *Auditor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := a.now()
+		entry := &auditEntry{decision: DecisionAllow, reason: "no authentication required"}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auditKey, entry)))
+
+		route := entry.route
+		if route == "" {
+			route = r.URL.Path
+		}
+		level := slog.LevelInfo
+		if entry.decision == DecisionDeny {
+			level = slog.LevelWarn
+		}
+		a.logger.LogAttrs(r.Context(), level, auditMessage,
+			slog.String(auditKeyPrincipal, entry.principal),
+			slog.String(auditKeyMethod, r.Method),
+			slog.String(auditKeyRoute, route),
+			slog.String(auditKeyDecision, string(entry.decision)),
+			slog.String(auditKeyReason, entry.reason),
+			slog.Duration(auditKeyLatency, a.now().Sub(start)),
+		)
+	})
+}
+
+// auditDecision reports a decision on the request of ctx to the Auditor
+// middleware, if any.
+func auditDecision(ctx context.Context, principal string, decision Decision, reason string) {
+	if entry, ok := ctx.Value(auditKey).(*auditEntry); ok {
+		entry.principal, entry.decision, entry.reason = principal, decision, reason
+	}
+}
+
+// auditRoute returns a handler that reports the pattern of the route that
+// matched the request to the Auditor middleware, if any, before calling next.
+func auditRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry, ok := r.Context().Value(auditKey).(*auditEntry); ok {
+			entry.route = r.Pattern
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuditFile is an audit sink that appends events to a file as JSON lines.
+type AuditFile struct {
+	f       *os.File
+	handler slog.Handler
+}
+
+// OpenAuditFile opens the file at path for appending audit events, creating it
+// if needed.
+func OpenAuditFile(path string) (*AuditFile, error) {
+	//nolint:gosec // The path is trusted configuration.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditFile{f: f, handler: slog.NewJSONHandler(f, nil)}, nil
+}
+
+// Handler returns the slog handler that writes to the file.
+func (a *AuditFile) Handler() slog.Handler {
+	return a.handler
+}
+
+// Close closes the file.
+func (a *AuditFile) Close() error {
+	return a.f.Close()
+}
+
+// MemoryAuditSink is an audit sink that keeps events in memory, for tests.
+// It is safe for concurrent use.
+type MemoryAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// Events returns the events received so far.
+func (s *MemoryAuditSink) Events() []AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEvent(nil), s.events...)
+}
+
+func (s *MemoryAuditSink) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (s *MemoryAuditSink) Handle(_ context.Context, record slog.Record) error {
+	event := AuditEvent{Time: record.Time}
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case auditKeyPrincipal:
+			event.Principal = attr.Value.String()
+		case auditKeyMethod:
+			event.Method = attr.Value.String()
+		case auditKeyRoute:
+			event.Route = attr.Value.String()
+		case auditKeyDecision:
+			event.Decision = Decision(attr.Value.String())
+		case auditKeyReason:
+			event.Reason = attr.Value.String()
+		case auditKeyLatency:
+			event.Latency = attr.Value.Duration()
+		}
+		return true
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// WithAttrs and WithGroup return the sink itself, which only keeps the
+// attributes of audit events.
+func (s *MemoryAuditSink) WithAttrs([]slog.Attr) slog.Handler {
+	return s
+}
+
+func (s *MemoryAuditSink) WithGroup(string) slog.Handler {
+	return s
+}
@@ -0,0 +1,178 @@
+package challenge05
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditor(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	auditor := NewAuditor(sink)
+	handler := auditor.Middleware(setupPolicyRouter())
+
+	testCases := []struct {
+		method string
+		url    string
+		token  string
+		want   AuditEvent
+	}{
+		{
+			method: http.MethodGet,
+			url:    "/hello",
+			want: AuditEvent{
+				Method:   http.MethodGet,
+				Route:    "GET /hello",
+				Decision: DecisionAllow,
+				Reason:   "no authentication required",
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/reports",
+			want: AuditEvent{
+				Method:   http.MethodGet,
+				Route:    "GET /reports",
+				Decision: DecisionDeny,
+				Reason:   "missing token",
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/reports",
+			token:  "guess",
+			want: AuditEvent{
+				Method:   http.MethodGet,
+				Route:    "GET /reports",
+				Decision: DecisionDeny,
+				Reason:   "invalid token",
+			},
+		},
+		{
+			method: http.MethodPost,
+			url:    "/reports",
+			token:  "reader",
+			want: AuditEvent{
+				Principal: "rob",
+				Method:    http.MethodPost,
+				Route:     "POST /reports",
+				Decision:  DecisionDeny,
+				Reason:    "forbidden: requires one of roles admin",
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/reports",
+			token:  "reader",
+			want: AuditEvent{
+				Principal: "rob",
+				Method:    http.MethodGet,
+				Route:     "GET /reports",
+				Decision:  DecisionAllow,
+				Reason:    "authorized",
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/profile",
+			token:  "nobody",
+			want: AuditEvent{
+				Principal: "ned",
+				Method:    http.MethodGet,
+				Route:     "GET /profile",
+				Decision:  DecisionAllow,
+				Reason:    "authenticated",
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/missing",
+			want: AuditEvent{
+				Method:   http.MethodGet,
+				Route:    "/missing",
+				Decision: DecisionAllow,
+				Reason:   "no authentication required",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		if tc.token != "" {
+			req.Header.Set("X-Auth-Token", tc.token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	events := sink.Events()
+	if len(events) != len(testCases) {
+		t.Fatalf("Expected %d events, got %d", len(testCases), len(events))
+	}
+	for i, tc := range testCases {
+		got := events[i]
+		if got.Time.IsZero() || got.Latency < 0 {
+			t.Errorf("Event %d: expected a time and latency, got %+v", i, got)
+		}
+		got.Time, got.Latency = time.Time{}, 0
+		if got != tc.want {
+			t.Errorf("Event %d: expected %+v, got %+v", i, tc.want, got)
+		}
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := OpenAuditFile(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit file: %v", err)
+	}
+	auditor := NewAuditor(file.Handler())
+	handler := auditor.Middleware(SetupServer())
+	for _, token := range []string{"secret", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+		req.Header.Set("X-Auth-Token", token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	wantLevels := []string{slog.LevelInfo.String(), slog.LevelWarn.String()}
+	wantPrincipals := []string{"default", ""}
+	for i, line := range lines {
+		if line["msg"] != "auth decision" || line["route"] != "/secure" {
+			t.Errorf("Unexpected line %v", line)
+		}
+		if line["level"] != wantLevels[i] {
+			t.Errorf("Expected level %q, got %v", wantLevels[i], line["level"])
+		}
+		if line["principal"] != wantPrincipals[i] {
+			t.Errorf("Expected principal %q, got %v", wantPrincipals[i], line["principal"])
+		}
+	}
+}
@@ -1,28 +1,168 @@
 package challenge05
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 )
 
 const validToken = "secret"
 
+// contextKey is an unexported type for context keys in this package.
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Principal is an authenticated caller.
+type Principal struct {
+	ID     string
+	Roles  []string
+	Scopes []string
+	// Claims holds the claims of the token the caller presented, for tokens
+	// that carry any, such as JWTs.
+	Claims map[string]any
+}
+
+// PrincipalFromContext returns the principal authenticated by the middleware
+// created with NewAuthMiddleware, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(Principal)
+	return principal, ok
+}
+
+// AuthOption configures a middleware created with NewAuthMiddleware.
+type AuthOption func(*authConfig)
+
+type authConfig struct {
+	token func(r *http.Request) string
+	// scheme is the authentication scheme announced in the WWW-Authenticate
+	// header of 401 responses, if any.
+	scheme  string
+	limiter *RateLimiter
+	// certPrincipal maps verified client certificates to principals, if they
+	// are accepted.
+	certPrincipal CertPrincipalFunc
+}
+
+// WithBearerToken takes the token from the "Authorization: Bearer <token>"
+// header (RFC 6750) instead of the "X-Auth-Token" header.
+func WithBearerToken() AuthOption {
+	return func(c *authConfig) {
+		c.token = bearerToken
+		c.scheme = "Bearer"
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// NewAuthMiddleware returns a middleware that authenticates requests with
+// validator, by their "X-Auth-Token" header unless opts say otherwise. It
+// responds with 401 Unauthorized if the token is missing or invalid, and
+// otherwise calls the next handler with the principal stored in the request
+// context.
+func NewAuthMiddleware(
+	validator TokenValidator,
+	opts ...AuthOption,
+) func(http.Handler) http.Handler {
+	config := authConfig{
+		token: func(r *http.Request) string { return r.Header.Get("X-Auth-Token") },
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := config.authenticate(w, r, validator)
+			if !ok {
+				return
+			}
+			ctx := context.WithValue(r.Context(), principalKey, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticate returns the principal that r authenticates as, or writes the
+// error response and returns false.
+func (c authConfig) authenticate(
+	w http.ResponseWriter,
+	r *http.Request,
+	validator TokenValidator,
+) (Principal, bool) {
+	if c.certPrincipal != nil {
+		if cert := verifiedClientCert(r); cert != nil {
+			return authenticateCert(w, r, cert, c.certPrincipal)
+		}
+	}
+	token := c.token(r)
+	var keys []string
+	if c.limiter != nil {
+		keys = c.limiter.attemptKeys(r, token, validator)
+		if retryAfter, ok := c.limiter.admit(keys); !ok {
+			auditDecision(r.Context(), "", DecisionDeny, "too many attempts")
+			tooManyRequests(w, retryAfter)
+			return Principal{}, false
+		}
+	}
+	if token == "" {
+		auditDecision(r.Context(), "", DecisionDeny, "missing token")
+		c.unauthorized(w, "")
+		return Principal{}, false
+	}
+
+	principal, err := validator.ValidateToken(r.Context(), token)
+	if errors.Is(err, ErrInvalidToken) {
+		if c.limiter != nil {
+			c.limiter.recordFailure(keys)
+		}
+		auditDecision(r.Context(), "", DecisionDeny, err.Error())
+		c.unauthorized(w, "invalid_token")
+		return Principal{}, false
+	}
+	if err != nil {
+		log.Printf("token validation error: %v", err)
+		auditDecision(r.Context(), "", DecisionDeny, "token validation error")
+		http.Error(w, "", http.StatusInternalServerError)
+		return Principal{}, false
+	}
+	if c.limiter != nil {
+		c.limiter.recordSuccess(keys)
+	}
+	auditDecision(r.Context(), principal.ID, DecisionAllow, "authenticated")
+	return principal, true
+}
+
+func (c authConfig) unauthorized(w http.ResponseWriter, errorCode string) {
+	if c.scheme != "" {
+		challenge := c.scheme
+		if errorCode != "" {
+			challenge += ` error="` + errorCode + `"`
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	http.Error(w, "", http.StatusUnauthorized)
+}
+
 // AuthMiddleware checks the "X-Auth-Token" header.
 // If it's "secret", call the next handler.
 // Otherwise, respond with 401 Unauthorized.
 func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement the logic:
-		//  1) Grab the "X-Auth-Token" header
-		//  2) Compare against validToken
-		//  3) If mismatch or missing, respond with 401
-		//  4) Otherwise pass to next handler
-		if r.Header.Get("X-Auth-Token") != validToken {
-			http.Error(w, "", http.StatusUnauthorized)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+	return NewAuthMiddleware(defaultValidator())(next)
+}
+
+// defaultValidator accepts the "secret" token, for the "default" principal.
+func defaultValidator() *StaticTokenValidator {
+	return NewStaticTokenValidator(map[string]Principal{validToken: {ID: "default"}})
 }
 
 // helloHandler returns "Hello!" on GET /hello
@@ -40,16 +180,28 @@ func secureHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // SetupServer configures the HTTP routes with the authentication middleware.
-func SetupServer() http.Handler {
-	mux := http.NewServeMux()
+// opts configure authentication, such as WithClientCert to also accept client
+// certificates.
+func SetupServer(opts ...AuthOption) http.Handler {
+	router := NewRouter(NewAuthMiddleware(defaultValidator(), opts...))
 
 	// Public route: /hello (no auth required)
-	mux.HandleFunc("/hello", helloHandler)
+	router.HandlePublic("/hello", http.HandlerFunc(helloHandler))
 
 	// Secure route: /secure
-	// Wrap with AuthMiddleware
-	secureRoute := http.HandlerFunc(secureHandler)
-	mux.Handle("/secure", AuthMiddleware(secureRoute))
+	// Authenticated by the "secret" token, or as opts allow
+	router.Handle("/secure", http.HandlerFunc(secureHandler))
+
+	return router
+}
 
-	return mux
+// SetupServerWithAPIKeys configures the routes of SetupServer, accepting the
+// keys of store as well as the static token, and the API key admin API of
+// store for principals with the "admin" role.
+func SetupServerWithAPIKeys(store *APIKeyStore) http.Handler {
+	router := NewRouter(NewAuthMiddleware(ValidatorChain{defaultValidator(), store}))
+	router.HandlePublic("/hello", http.HandlerFunc(helloHandler))
+	router.Handle("/secure", http.HandlerFunc(secureHandler))
+	store.RegisterAdminRoutes(router, RequireRoles("admin"))
+	return router
 }
@@ -0,0 +1,164 @@
+package challenge05
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Policy is an authorization rule on authenticated principals. The zero Policy
+// lets every authenticated principal through.
+type Policy struct {
+	// Roles lists the roles of which the principal must have at least one.
+	Roles []string
+	// Scopes lists the scopes the principal must all have.
+	Scopes []string
+}
+
+// RequireRoles returns a policy that requires any of roles.
+func RequireRoles(roles ...string) Policy {
+	return Policy{Roles: roles}
+}
+
+// RequireScopes returns a policy that requires all of scopes.
+func RequireScopes(scopes ...string) Policy {
+	return Policy{Scopes: scopes}
+}
+
+// PolicyError describes why a principal does not satisfy a Policy.
+type PolicyError struct {
+	// RequiredRoles is set when the principal has none of the required roles.
+	RequiredRoles []string
+	// MissingScopes lists the required scopes the principal does not have.
+	MissingScopes []string
+}
+
+func (e *PolicyError) Error() string {
+	var reasons []string
+	if len(e.RequiredRoles) > 0 {
+		reasons = append(reasons, "requires one of roles "+strings.Join(e.RequiredRoles, ", "))
+	}
+	if len(e.MissingScopes) > 0 {
+		reasons = append(reasons, "missing scopes "+strings.Join(e.MissingScopes, ", "))
+	}
+	return "forbidden: " + strings.Join(reasons, "; ")
+}
+
+// Check returns a *PolicyError if principal does not satisfy the policy.
+func (p Policy) Check(principal Principal) error {
+	if err := p.check(principal); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p Policy) check(principal Principal) *PolicyError {
+	var e PolicyError
+	if len(p.Roles) > 0 &&
+		!slices.ContainsFunc(
+			p.Roles,
+			func(r string) bool { return slices.Contains(principal.Roles, r) },
+		) {
+		e.RequiredRoles = p.Roles
+	}
+	for _, s := range p.Scopes {
+		if !slices.Contains(principal.Scopes, s) {
+			e.MissingScopes = append(e.MissingScopes, s)
+		}
+	}
+	if e.RequiredRoles == nil && e.MissingScopes == nil {
+		return nil
+	}
+	return &e
+}
+
+// PolicyErrorResponse is the body of a 403 Forbidden response.
+type PolicyErrorResponse struct {
+	Error         string   `json:"error"`
+	RequiredRoles []string `json:"requiredRoles,omitempty"`
+	MissingScopes []string `json:"missingScopes,omitempty"`
+}
+
+// Authorize returns a middleware that lets requests through only if the
+// principal in their context satisfies every policy. It responds with 401
+// Unauthorized if there is no principal, so it must come after an
+// authentication middleware, and with 403 Forbidden if a policy is not met.
+func Authorize(policies ...Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				auditDecision(r.Context(), "", DecisionDeny, "not authenticated")
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			for _, policy := range policies {
+				if err := policy.check(principal); err != nil {
+					auditDecision(r.Context(), principal.ID, DecisionDeny, err.Error())
+					writeForbidden(w, err)
+					return
+				}
+			}
+			if len(policies) > 0 {
+				auditDecision(r.Context(), principal.ID, DecisionAllow, "authorized")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeForbidden(w http.ResponseWriter, err *PolicyError) {
+	writeJSON(w, http.StatusForbidden, PolicyErrorResponse{
+		Error:         err.Error(),
+		RequiredRoles: err.RequiredRoles,
+		MissingScopes: err.MissingScopes,
+	})
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
+
+// Router registers routes together with their authentication and
+// authorization requirements. Patterns are those of http.ServeMux, so that
+// "GET /reports" and "POST /reports" can have different requirements.
+type Router struct {
+	mux          *http.ServeMux
+	authenticate func(http.Handler) http.Handler
+}
+
+// NewRouter returns a router that authenticates the requests to non-public
+// routes with authenticate, such as a middleware created by NewAuthMiddleware.
+func NewRouter(authenticate func(http.Handler) http.Handler) *Router {
+	return &Router{mux: http.NewServeMux(), authenticate: authenticate}
+}
+
+// HandlePublic registers a route that does not require authentication.
+func (rt *Router) HandlePublic(pattern string, handler http.Handler) {
+	rt.mux.Handle(pattern, auditRoute(handler))
+}
+
+// Handle registers a route that requires authentication, and that the
+// principal satisfies every policy.
+func (rt *Router) Handle(pattern string, handler http.Handler, policies ...Policy) {
+	rt.mux.Handle(pattern, auditRoute(rt.authenticate(Authorize(policies...)(handler))))
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
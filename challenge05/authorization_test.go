@@ -0,0 +1,190 @@
+package challenge05
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func setupPolicyRouter() *Router {
+	validator := NewStaticTokenValidator(map[string]Principal{
+		"admin":  {ID: "ann", Roles: []string{"admin"}, Scopes: []string{"reports:read"}},
+		"reader": {ID: "rob", Roles: []string{"viewer"}, Scopes: []string{"reports:read"}},
+		"nobody": {ID: "ned"},
+	})
+	router := NewRouter(NewAuthMiddleware(validator))
+	router.HandlePublic("GET /hello", http.HandlerFunc(helloHandler))
+	router.Handle(
+		"GET /reports",
+		http.HandlerFunc(secureHandler),
+		RequireRoles("admin", "viewer"),
+		RequireScopes("reports:read"),
+	)
+	router.Handle("POST /reports", http.HandlerFunc(secureHandler), RequireRoles("admin"))
+	router.Handle("GET /profile", http.HandlerFunc(secureHandler))
+	return router
+}
+
+func TestRouterPolicies(t *testing.T) {
+	router := setupPolicyRouter()
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		token      string
+		wantStatus int
+		wantBody   PolicyErrorResponse
+	}{
+		{name: "Public", method: http.MethodGet, url: "/hello", wantStatus: http.StatusOK},
+		{
+			name:       "Unauthenticated",
+			method:     http.MethodGet,
+			url:        "/reports",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Invalid token",
+			method:     http.MethodPost,
+			url:        "/reports",
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Any of the roles",
+			method:     http.MethodGet,
+			url:        "/reports",
+			token:      "reader",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Role required by method",
+			method:     http.MethodPost,
+			url:        "/reports",
+			token:      "reader",
+			wantStatus: http.StatusForbidden,
+			wantBody: PolicyErrorResponse{
+				Error:         "forbidden: requires one of roles admin",
+				RequiredRoles: []string{"admin"},
+			},
+		},
+		{
+			name:       "Admin",
+			method:     http.MethodPost,
+			url:        "/reports",
+			token:      "admin",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "No roles or scopes",
+			method:     http.MethodGet,
+			url:        "/reports",
+			token:      "nobody",
+			wantStatus: http.StatusForbidden,
+			wantBody: PolicyErrorResponse{
+				Error:         "forbidden: requires one of roles admin, viewer",
+				RequiredRoles: []string{"admin", "viewer"},
+			},
+		},
+		{
+			name:       "Authentication only",
+			method:     http.MethodGet,
+			url:        "/profile",
+			token:      "nobody",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Method not allowed",
+			method:     http.MethodDelete,
+			url:        "/reports",
+			token:      "admin",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if tc.token != "" {
+				req.Header.Set("X-Auth-Token", tc.token)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if tc.wantStatus != http.StatusForbidden {
+				return
+			}
+			var got PolicyErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.wantBody) {
+				t.Errorf("Expected %+v, got %+v", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	principal := Principal{ID: "p", Roles: []string{"a"}, Scopes: []string{"x"}}
+
+	testCases := []struct {
+		name   string
+		policy Policy
+		want   *PolicyError
+	}{
+		{name: "Zero policy", policy: Policy{}},
+		{name: "Role and scope", policy: Policy{Roles: []string{"b", "a"}, Scopes: []string{"x"}}},
+		{
+			name:   "Missing scopes",
+			policy: RequireScopes("x", "y", "z"),
+			want:   &PolicyError{MissingScopes: []string{"y", "z"}},
+		},
+		{
+			name:   "Missing role and scope",
+			policy: Policy{Roles: []string{"b"}, Scopes: []string{"y"}},
+			want:   &PolicyError{RequiredRoles: []string{"b"}, MissingScopes: []string{"y"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(principal)
+			if tc.want == nil {
+				if err != nil {
+					t.Errorf("Did not expect error but got: %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestJWTRolesAndScopes(t *testing.T) {
+	keys := &KeySet{keys: map[string]jwk{"": {alg: algHS256, hmacKey: testHMACKey}}}
+	token := signJWT(t, "HS256", "", testHMACKey, map[string]any{
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"roles": []string{"admin"},
+		"scope": "reports:read reports:write",
+	})
+	principal, err := NewJWTValidator(JWTConfig{Keys: keys}).ValidateToken(t.Context(), token)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if !reflect.DeepEqual(principal.Roles, []string{"admin"}) {
+		t.Errorf("Expected roles [admin], got %v", principal.Roles)
+	}
+	if !reflect.DeepEqual(principal.Scopes, []string{"reports:read", "reports:write"}) {
+		t.Errorf("Expected scopes [reports:read reports:write], got %v", principal.Scopes)
+	}
+}
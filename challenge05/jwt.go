@@ -0,0 +1,278 @@
+package challenge05
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Signing algorithms supported by JWTValidator.
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+// minRSAKeyBits is the smallest RSA key a key set accepts.
+const minRSAKeyBits = 2048
+
+// JWTConfig configures a JWTValidator.
+type JWTConfig struct {
+	// Keys verifies token signatures.
+	Keys *KeySet
+	// Issuer and Audience, when not empty, must match the "iss" claim and be
+	// one of the "aud" claim values respectively.
+	Issuer   string
+	Audience string
+	// ClockSkew is how far the "exp" and "nbf" claims may be off.
+	ClockSkew time.Duration
+}
+
+// JWTValidator accepts JSON Web Tokens signed with HS256 or RS256 by a key of
+// its key set. Tokens must have "sub" and "exp" claims. The principal ID is the
+// subject, its roles come from the "roles" claim, its scopes from the
+// space-separated "scope" claim (RFC 8693), and Principal.Claims holds every
+// claim of the token.
+type JWTValidator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTValidator returns a validator for the tokens accepted by config.
+func NewJWTValidator(config JWTConfig) *JWTValidator {
+	return &JWTValidator{config: config, now: time.Now}
+}
+
+// NewJWTMiddleware returns a middleware that authenticates requests by the JWT
+// in their "Authorization: Bearer" header. See NewAuthMiddleware.
+func NewJWTMiddleware(config JWTConfig) func(http.Handler) http.Handler {
+	return NewAuthMiddleware(NewJWTValidator(config), WithBearerToken())
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type registeredClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+	Scope     string   `json:"scope"`
+}
+
+// audience is the "aud" claim, which is either a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+func (v *JWTValidator) ValidateToken(_ context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: malformed JWT", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed JWT header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed JWT signature", ErrInvalidToken)
+	}
+	if err := v.config.Keys.verify(header, parts[0]+"."+parts[1], sig); err != nil {
+		return Principal{}, err
+	}
+
+	// Only look at the claims once the signature is known to be good.
+	var registered registeredClaims
+	var claims map[string]any
+	if decodeSegment(parts[1], &registered) != nil || decodeSegment(parts[1], &claims) != nil {
+		return Principal{}, fmt.Errorf("%w: malformed JWT claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(registered); err != nil {
+		return Principal{}, err
+	}
+	return Principal{
+		ID:     registered.Subject,
+		Roles:  registered.Roles,
+		Scopes: strings.Fields(registered.Scope),
+		Claims: claims,
+	}, nil
+}
+
+func (v *JWTValidator) checkClaims(claims registeredClaims) error {
+	now := v.now()
+	switch {
+	case claims.Subject == "":
+		return fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	case claims.ExpiresAt == nil:
+		return fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	case !now.Before(numericDate(*claims.ExpiresAt).Add(v.config.ClockSkew)):
+		return fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case claims.NotBefore != nil &&
+		now.Add(v.config.ClockSkew).Before(numericDate(*claims.NotBefore)):
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidToken)
+	case v.config.Issuer != "" && claims.Issuer != v.config.Issuer:
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	case v.config.Audience != "" && !slices.Contains(claims.Audience, v.config.Audience):
+		return fmt.Errorf("%w: token is not for audience %q", ErrInvalidToken, v.config.Audience)
+	}
+	return nil
+}
+
+// numericDate converts a JWT NumericDate, in seconds since the epoch.
+func numericDate(seconds float64) time.Time {
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// KeySet holds the keys that verify JWT signatures, by key ID.
+type KeySet struct {
+	keys map[string]jwk
+}
+
+type jwk struct {
+	alg     string
+	hmacKey []byte
+	rsaKey  *rsa.PublicKey
+}
+
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadKeySetFile loads a KeySet from the file at path. See LoadKeySet for the format.
+func LoadKeySetFile(path string) (*KeySet, error) {
+	f, err := os.Open(path) //nolint:gosec // The path is trusted configuration.
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return LoadKeySet(f)
+}
+
+// LoadKeySet loads a KeySet from a JSON Web Key Set (RFC 7517). Symmetric
+// ("oct") keys verify HS256 signatures, and RSA public keys verify RS256
+// signatures. Every key must have a distinct "kid", except that a set with a
+// single key may omit it.
+func LoadKeySet(r io.Reader) (*KeySet, error) {
+	var set struct {
+		Keys []jwkJSON `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("key set has no keys")
+	}
+
+	ks := &KeySet{keys: make(map[string]jwk, len(set.Keys))}
+	for i, raw := range set.Keys {
+		if raw.Kid == "" && len(set.Keys) > 1 {
+			return nil, fmt.Errorf("key %d: missing kid", i)
+		}
+		if _, ok := ks.keys[raw.Kid]; ok {
+			return nil, fmt.Errorf("key %d: duplicate kid %q", i, raw.Kid)
+		}
+		key, err := parseJWK(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		ks.keys[raw.Kid] = key
+	}
+	return ks, nil
+}
+
+func parseJWK(raw jwkJSON) (jwk, error) {
+	var key jwk
+	switch raw.Kty {
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(raw.K)
+		if err != nil || len(k) == 0 {
+			return jwk{}, errors.New("invalid symmetric key")
+		}
+		key = jwk{alg: algHS256, hmacKey: k}
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(raw.N)
+		e, errE := base64.RawURLEncoding.DecodeString(raw.E)
+		exp := new(big.Int).SetBytes(e)
+		if errN != nil || errE != nil || !exp.IsInt64() || exp.Int64() < 3 ||
+			exp.Int64() > math.MaxInt32 {
+			return jwk{}, errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+		if pub.N.BitLen() < minRSAKeyBits {
+			return jwk{}, fmt.Errorf("RSA key shorter than %d bits", minRSAKeyBits)
+		}
+		key = jwk{alg: algRS256, rsaKey: pub}
+	default:
+		return jwk{}, fmt.Errorf("unsupported key type %q", raw.Kty)
+	}
+	if raw.Alg != "" && raw.Alg != key.alg {
+		return jwk{}, fmt.Errorf("unsupported algorithm %q for key type %q", raw.Alg, raw.Kty)
+	}
+	return key, nil
+}
+
+// verify checks the signature of a JWT. The algorithm is dictated by the key,
+// so that a token cannot pick a weaker one than the key was issued for.
+func (ks *KeySet) verify(header jwtHeader, signingInput string, sig []byte) error {
+	key, ok := ks.keys[header.Kid]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidToken, header.Kid)
+	}
+	if header.Alg != key.alg {
+		return fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	var valid bool
+	switch key.alg {
+	case algHS256:
+		h := hmac.New(sha256.New, key.hmacKey)
+		h.Write([]byte(signingInput))
+		valid = hmac.Equal(sig, h.Sum(nil))
+	case algRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		valid = rsa.VerifyPKCS1v15(key.rsaKey, crypto.SHA256, digest[:], sig) == nil
+	}
+	if !valid {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
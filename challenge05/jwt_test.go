@@ -0,0 +1,295 @@
+package challenge05
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testHMACKey = []byte("0123456789abcdef0123456789abcdef")
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signJWT signs claims with key, which is either an HMAC key or an *rsa.PrivateKey.
+func signJWT(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := b64(header) + "." + b64(payload)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		h := hmac.New(sha256.New, k)
+		h.Write([]byte(signingInput))
+		sig = h.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signingInput + "." + b64(sig)
+}
+
+func writeKeySet(t *testing.T, rsaKey *rsa.PrivateKey) string {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "alg": "HS256", "k": b64(testHMACKey)},
+		{
+			"kty": "RSA",
+			"kid": "rs",
+			"n":   b64(rsaKey.N.Bytes()),
+			"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTValidator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeySetFile(writeKeySet(t, rsaKey))
+	if err != nil {
+		t.Fatalf("Failed to load key set: %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	v := NewJWTValidator(JWTConfig{
+		Keys:      keys,
+		Issuer:    "https://issuer.example",
+		Audience:  "api",
+		ClockSkew: 30 * time.Second,
+	})
+	v.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":  "https://issuer.example",
+			"sub":  "alice",
+			"aud":  []string{"api", "admin"},
+			"exp":  now.Add(time.Minute).Unix(),
+			"nbf":  now.Unix(),
+			"role": "reader",
+		}
+		for k, val := range overrides {
+			if val == nil {
+				delete(c, k)
+			} else {
+				c[k] = val
+			}
+		}
+		return c
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "HS256", token: signJWT(t, "HS256", "hs", testHMACKey, claims(nil)), valid: true},
+		{name: "RS256", token: signJWT(t, "RS256", "rs", rsaKey, claims(nil)), valid: true},
+		{
+			name:  "Single audience",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{"aud": "api"})),
+			valid: true,
+		},
+		{
+			name: "Expired within skew",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{
+				"exp": now.Add(-10 * time.Second).Unix(),
+			})),
+			valid: true,
+		},
+		{
+			name: "Expired",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{
+				"exp": now.Add(-time.Minute).Unix(),
+			})),
+		},
+		{
+			name: "Not valid yet within skew",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{
+				"nbf": now.Add(10 * time.Second).Unix(),
+			})),
+			valid: true,
+		},
+		{
+			name: "Not valid yet",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{
+				"nbf": now.Add(time.Minute).Unix(),
+			})),
+		},
+		{
+			name:  "Missing expiry",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{"exp": nil})),
+		},
+		{
+			name:  "Missing subject",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{"sub": nil})),
+		},
+		{
+			name:  "Wrong issuer",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{"iss": "evil"})),
+		},
+		{
+			name:  "Wrong audience",
+			token: signJWT(t, "HS256", "hs", testHMACKey, claims(map[string]any{"aud": "other"})),
+		},
+		{
+			name:  "Unknown key",
+			token: signJWT(t, "HS256", "nope", testHMACKey, claims(nil)),
+		},
+		{
+			name:  "Wrong HMAC key",
+			token: signJWT(t, "HS256", "hs", []byte("wrong"), claims(nil)),
+		},
+		{
+			name:  "Algorithm does not match key",
+			token: signJWT(t, "HS256", "rs", rsaKey.N.Bytes(), claims(nil)),
+		},
+		{
+			name:  "Algorithm none",
+			token: signJWT(t, "none", "hs", nil, claims(nil)),
+		},
+		{name: "Malformed", token: "a.b"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !tc.valid {
+				assertInvalid(t, v, tc.token)
+				return
+			}
+			assertValid(t, v, tc.token, "alice")
+		})
+	}
+
+	t.Run("Claims", func(t *testing.T) {
+		principal, err := v.ValidateToken(t.Context(), testCases[0].token)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if principal.Claims["role"] != "reader" {
+			t.Errorf("Expected role claim %q, got %v", "reader", principal.Claims["role"])
+		}
+	})
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Not JSON", data: "keys"},
+		{name: "No keys", data: `{"keys": []}`},
+		{
+			name: "Missing kid",
+			data: `{"keys": [{"kty": "oct", "k": "a2V5"}, {"kty": "oct", "k": "a2V5"}]}`,
+		},
+		{
+			name: "Duplicate kid",
+			data: `{"keys": [{"kty": "oct", "kid": "a", "k": "a2V5"}, {"kty": "oct", "kid": "a", "k": "a2V5"}]}`,
+		},
+		{name: "Unsupported key type", data: `{"keys": [{"kty": "EC"}]}`},
+		{
+			name: "Mismatched algorithm",
+			data: `{"keys": [{"kty": "oct", "alg": "RS256", "k": "a2V5"}]}`,
+		},
+		{name: "Short RSA key", data: `{"keys": [{"kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := LoadKeySet(strings.NewReader(tc.data)); err == nil {
+				t.Error("Expected error but got nil")
+			}
+		})
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	keys, err := LoadKeySet(
+		strings.NewReader(`{"keys": [{"kty": "oct", "k": "` + b64(testHMACKey) + `"}]}`),
+	)
+	if err != nil {
+		t.Fatalf("Failed to load key set: %v", err)
+	}
+	token := signJWT(t, "HS256", "", testHMACKey, map[string]any{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	handler := NewJWTMiddleware(JWTConfig{Keys: keys})(http.HandlerFunc(secureHandler))
+
+	testCases := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{name: "Valid", authorization: "Bearer " + token, wantStatus: http.StatusOK},
+		{name: "Lowercase scheme", authorization: "bearer " + token, wantStatus: http.StatusOK},
+		{name: "Missing", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{
+			name:          "Other scheme",
+			authorization: "Basic " + token,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer",
+		},
+		{
+			name:          "Invalid",
+			authorization: "Bearer " + token + "x",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); got != tc.wantChallenge {
+				t.Errorf("Expected WWW-Authenticate %q, got %q", tc.wantChallenge, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package challenge05
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidCertificate is returned by a CertPrincipalFunc for a client
+// certificate it does not accept.
+var ErrInvalidCertificate = errors.New("invalid client certificate")
+
+// CertPrincipalFunc maps a verified client certificate to the principal it
+// authenticates, or returns an error if the certificate is not accepted.
+type CertPrincipalFunc func(cert *x509.Certificate) (Principal, error)
+
+// PrincipalFromCert is the default CertPrincipalFunc. The principal ID is the
+// first URI SAN of the certificate, such as a SPIFFE ID, or else its first DNS
+// SAN, email SAN or subject common name. It grants no roles, not even from the
+// organizational units of the subject, since whoever issues the certificates
+// is not necessarily trusted to grant them: wrap it in a CertPrincipalFunc to
+// map principals to roles.
+func PrincipalFromCert(cert *x509.Certificate) (Principal, error) {
+	var id string
+	switch {
+	case len(cert.URIs) > 0:
+		id = cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		id = cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		id = cert.EmailAddresses[0]
+	default:
+		id = cert.Subject.CommonName
+	}
+	if id == "" {
+		return Principal{}, ErrInvalidCertificate
+	}
+	return Principal{ID: id}, nil
+}
+
+// ClientCertTLSConfig returns a TLS server configuration that verifies the
+// client certificates against clientCAs. Clients without a certificate may
+// still connect, so that they can authenticate by token instead.
+func ClientCertTLSConfig(clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+}
+
+// WithClientCert authenticates the requests that come with a verified client
+// certificate by the principal that certPrincipal maps it to, and only the
+// other requests by their token. Pass nil to use PrincipalFromCert.
+func WithClientCert(certPrincipal CertPrincipalFunc) AuthOption {
+	if certPrincipal == nil {
+		certPrincipal = PrincipalFromCert
+	}
+	return func(c *authConfig) {
+		c.certPrincipal = certPrincipal
+	}
+}
+
+// NewClientCertMiddleware returns a middleware that authenticates requests by
+// their verified client certificate, mapped to a principal by certPrincipal,
+// or PrincipalFromCert if nil. It responds with 401 Unauthorized if there is
+// no such certificate or it is not accepted, and otherwise calls the next
+// handler with the principal stored in the request context.
+func NewClientCertMiddleware(certPrincipal CertPrincipalFunc) func(http.Handler) http.Handler {
+	if certPrincipal == nil {
+		certPrincipal = PrincipalFromCert
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cert := verifiedClientCert(r)
+			if cert == nil {
+				auditDecision(r.Context(), "", DecisionDeny, "missing client certificate")
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			principal, ok := authenticateCert(w, r, cert, certPrincipal)
+			if !ok {
+				return
+			}
+			ctx := context.WithValue(r.Context(), principalKey, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// verifiedClientCert returns the leaf certificate of the verified chain of the
+// TLS client of r, if any.
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// authenticateCert returns the principal that cert maps to, or writes the error
+// response and returns false.
+func authenticateCert(
+	w http.ResponseWriter,
+	r *http.Request,
+	cert *x509.Certificate,
+	certPrincipal CertPrincipalFunc,
+) (Principal, bool) {
+	principal, err := certPrincipal(cert)
+	if err != nil {
+		auditDecision(r.Context(), "", DecisionDeny, err.Error())
+		http.Error(w, "", http.StatusUnauthorized)
+		return Principal{}, false
+	}
+	auditDecision(r.Context(), principal.ID, DecisionAllow, "valid client certificate")
+	return principal, true
+}
@@ -0,0 +1,323 @@
+package challenge05
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority that issues client certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue returns a client certificate for template, signed by the CA.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// startTLSServer starts handler on a TLS server that verifies client
+// certificates issued by ca.
+func startTLSServer(t *testing.T, ca *testCA, handler http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = ClientCertTLSConfig(ca.pool())
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// tlsClient returns a client of server that presents certs.
+func tlsClient(server *httptest.Server, certs ...tls.Certificate) *http.Client {
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = certs
+	return &http.Client{Transport: transport}
+}
+
+func get(t *testing.T, client *http.Client, url, token string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestPrincipalFromCert(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/reports")
+	testCases := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{
+			name: "URI SAN",
+			cert: &x509.Certificate{
+				URIs:     []*url.URL{spiffe},
+				DNSNames: []string{"reports.example.org"},
+				Subject:  pkix.Name{CommonName: "reports"},
+			},
+			want: "spiffe://example.org/reports",
+		},
+		{
+			name: "DNS SAN",
+			cert: &x509.Certificate{
+				DNSNames:       []string{"reports.example.org"},
+				EmailAddresses: []string{"reports@example.org"},
+			},
+			want: "reports.example.org",
+		},
+		{
+			name: "Email SAN",
+			cert: &x509.Certificate{
+				EmailAddresses: []string{"reports@example.org"},
+				Subject:        pkix.Name{CommonName: "reports"},
+			},
+			want: "reports@example.org",
+		},
+		{
+			name: "Common name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "reports"}},
+			want: "reports",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := PrincipalFromCert(tc.cert)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if principal.ID != tc.want {
+				t.Errorf("Expected principal %q, got %q", tc.want, principal.ID)
+			}
+		})
+	}
+
+	withUnits := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "ops", OrganizationalUnit: []string{"admin"}},
+	}
+	if principal, _ := PrincipalFromCert(withUnits); len(principal.Roles) != 0 {
+		t.Errorf("Expected no roles from organizational units, got %v", principal.Roles)
+	}
+
+	if _, err := PrincipalFromCert(&x509.Certificate{}); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("Expected ErrInvalidCertificate for an anonymous certificate, got %v", err)
+	}
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	ca := newTestCA(t)
+	handler := NewClientCertMiddleware(nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := PrincipalFromContext(r.Context())
+			_, _ = io.WriteString(w, principal.ID)
+		}),
+	)
+	server := startTLSServer(t, ca, handler)
+
+	cert := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "reports"}})
+	status, body := get(t, tlsClient(server, cert), server.URL, "")
+	if status != http.StatusOK || body != "reports" {
+		t.Errorf("Expected status 200 for reports, got %d for %q", status, body)
+	}
+
+	anonymous := ca.issue(t, &x509.Certificate{})
+	status, _ = get(t, tlsClient(server, anonymous), server.URL, "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an anonymous certificate, got %d", status)
+	}
+
+	status, _ = get(t, tlsClient(server), server.URL, validToken)
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a certificate, got %d", status)
+	}
+}
+
+func TestClientCertFromUntrustedCA(t *testing.T) {
+	ca := newTestCA(t)
+	server := startTLSServer(t, ca, SetupServer(WithClientCert(nil)))
+
+	other := newTestCA(t)
+	cert := other.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}})
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/secure", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tlsClient(server, cert).Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the TLS handshake to fail")
+	}
+}
+
+func TestSetupServerWithClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	// Roles are granted by principal, not by what the certificate claims.
+	certPrincipal := func(cert *x509.Certificate) (Principal, error) {
+		principal, err := PrincipalFromCert(cert)
+		if principal.ID == "spiffe://example.org/ops" {
+			principal.Roles = []string{"admin"}
+		}
+		return principal, err
+	}
+	router := NewRouter(NewAuthMiddleware(defaultValidator(), WithClientCert(certPrincipal)))
+	router.Handle("GET /whoami", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		_, _ = io.WriteString(w, principal.ID)
+	}))
+	router.Handle("GET /admin", http.HandlerFunc(secureHandler), RequireRoles("admin"))
+	server := startTLSServer(t, ca, router)
+
+	spiffe, _ := url.Parse("spiffe://example.org/ops")
+	admin := ca.issue(t, &x509.Certificate{
+		URIs:    []*url.URL{spiffe},
+		Subject: pkix.Name{CommonName: "ops", OrganizationalUnit: []string{"admin"}},
+	})
+	reader := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "reader", OrganizationalUnit: []string{"admin"}},
+	})
+
+	testCases := []struct {
+		name       string
+		client     *http.Client
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Certificate",
+			client:     tlsClient(server, admin),
+			path:       "/whoami",
+			wantStatus: http.StatusOK,
+			wantBody:   "spiffe://example.org/ops",
+		},
+		{
+			name:       "Certificate takes precedence over token",
+			client:     tlsClient(server, reader),
+			path:       "/whoami",
+			token:      validToken,
+			wantStatus: http.StatusOK,
+			wantBody:   "reader",
+		},
+		{
+			name:       "Token without certificate",
+			client:     tlsClient(server),
+			path:       "/whoami",
+			token:      validToken,
+			wantStatus: http.StatusOK,
+			wantBody:   "default",
+		},
+		{
+			name:       "Neither",
+			client:     tlsClient(server),
+			path:       "/whoami",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Roles from the principal",
+			client:     tlsClient(server, admin),
+			path:       "/admin",
+			wantStatus: http.StatusOK,
+			wantBody:   "You are authorized!",
+		},
+		{
+			name:       "Organizational units are not roles",
+			client:     tlsClient(server, reader),
+			path:       "/admin",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := get(t, tc.client, server.URL+tc.path, tc.token)
+			if status != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tc.wantStatus, status)
+			}
+			if tc.wantBody != "" && body != tc.wantBody {
+				t.Errorf("Expected body %q, got %q", tc.wantBody, body)
+			}
+		})
+	}
+
+	// SetupServer accepts client certificates when asked to.
+	server = startTLSServer(t, ca, SetupServer(WithClientCert(nil)))
+	status, _ := get(t, tlsClient(server, reader), server.URL+"/secure", "")
+	if status != http.StatusOK {
+		t.Errorf("Expected status 200 with a certificate, got %d", status)
+	}
+	server = startTLSServer(t, ca, SetupServer())
+	status, _ = get(t, tlsClient(server, reader), server.URL+"/secure", "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 with a certificate by default, got %d", status)
+	}
+}
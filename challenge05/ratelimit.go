@@ -0,0 +1,240 @@
+package challenge05
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Default limits of a RateLimiter, used for zero RateLimitConfig fields.
+const (
+	defaultAttemptRate     = 5
+	defaultAttemptBurst    = 10
+	defaultMaxFailures     = 5
+	defaultLockoutDuration = 15 * time.Minute
+)
+
+// Prefixes of the keys that attempts count against.
+const (
+	ipKeyPrefix    = "ip:"
+	tokenKeyPrefix = "token:"
+)
+
+// sweepInterval is how often a RateLimiter forgets idle clients and tokens.
+const sweepInterval = time.Minute
+
+// RateLimitConfig configures a RateLimiter. Zero fields take the defaults.
+type RateLimitConfig struct {
+	// Rate and Burst bound the failed authentication attempts, with a token
+	// bucket per client IP and another per credential. Defaults to 5 per
+	// second with bursts of 10.
+	Rate  rate.Limit
+	Burst int
+	// MaxFailures failed attempts lock a client IP or credential out for
+	// LockoutDuration: from a client IP, failures that each come within
+	// LockoutDuration of the previous one, whether or not attempts succeed in
+	// between; with a credential, consecutive failures. Defaults to 5 and 15
+	// minutes.
+	MaxFailures     int
+	LockoutDuration time.Duration
+	// TokenKey returns the key of the credential that a token presents, which
+	// failed attempts count against across clients. Defaults to the key that
+	// the token validator returns if it is a CredentialKeyer, such as the ID
+	// of an API key, so that guesses of the secret of a key add up, and to a
+	// hash of the whole token otherwise.
+	TokenKey func(token string) string
+}
+
+func (c RateLimitConfig) withDefaults() RateLimitConfig {
+	if c.Rate <= 0 {
+		c.Rate = defaultAttemptRate
+	}
+	if c.Burst <= 0 {
+		c.Burst = defaultAttemptBurst
+	}
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = defaultMaxFailures
+	}
+	if c.LockoutDuration <= 0 {
+		c.LockoutDuration = defaultLockoutDuration
+	}
+	return c
+}
+
+// hashToken returns the key of a token that does not tell its credential.
+// Tokens of different users, such as JWTs, often share a prefix, so no fixed
+// part of a token identifies its credential.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RateLimiter throttles failed authentication attempts per client IP and per
+// credential, so that guessing tokens is slow both from one client and across
+// clients, and locks them out after repeated failures. Successful attempts are
+// not limited. The client IP is that of the connection; headers such as
+// X-Forwarded-For are not trusted.
+//
+// Clients and tokens that have been idle for LockoutDuration are forgotten,
+// along with their failures. It is safe for concurrent use.
+type RateLimiter struct {
+	config    RateLimitConfig
+	now       func() time.Time
+	mu        sync.Mutex
+	attempts  map[string]*attemptState
+	lastSweep time.Time
+}
+
+type attemptState struct {
+	limiter     *rate.Limiter
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	lastSeen    time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with the given limits.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		config:   config.withDefaults(),
+		now:      time.Now,
+		attempts: make(map[string]*attemptState),
+	}
+}
+
+// WithRateLimiter throttles authentication attempts with l. Throttled requests
+// get a 429 Too Many Requests response with a Retry-After header.
+func WithRateLimiter(l *RateLimiter) AuthOption {
+	return func(c *authConfig) {
+		c.limiter = l
+	}
+}
+
+// attemptKeys returns the keys that an authentication attempt with token from
+// the client of r, to be validated by validator, counts against.
+func (l *RateLimiter) attemptKeys(
+	r *http.Request,
+	token string,
+	validator TokenValidator,
+) []string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	keys := []string{ipKeyPrefix + host}
+	if token != "" {
+		keys = append(keys, tokenKeyPrefix+l.tokenKey(token, validator))
+	}
+	return keys
+}
+
+// tokenKey returns the key of the credential that token presents.
+func (l *RateLimiter) tokenKey(token string, validator TokenValidator) string {
+	if l.config.TokenKey != nil {
+		return l.config.TokenKey(token)
+	}
+	if keyer, ok := validator.(CredentialKeyer); ok {
+		if key, ok := keyer.CredentialKey(token); ok {
+			return key
+		}
+	}
+	return hashToken(token)
+}
+
+// admit returns true if an attempt against keys is allowed: none of them is
+// locked out, and none has used up its bucket of failures. Otherwise, it
+// returns how long until the attempt would be allowed. It does not consume from
+// the buckets, which only failures do.
+func (l *RateLimiter) admit(keys []string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, key := range keys {
+		s, ok := l.attempts[key]
+		if !ok {
+			continue
+		}
+		wait = max(wait, s.lockedUntil.Sub(now))
+		if tokens := s.limiter.TokensAt(now); tokens < 1 {
+			refill := (1 - tokens) / float64(l.config.Rate)
+			wait = max(wait, time.Duration(refill*float64(time.Second)))
+		}
+	}
+	return wait, wait <= 0
+}
+
+// recordFailure counts a failed attempt against keys, taking it from their
+// buckets and locking them out once they reach MaxFailures failures. The
+// failures of a key are forgotten once it has not failed for LockoutDuration.
+func (l *RateLimiter) recordFailure(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, key := range keys {
+		s := l.state(key, now)
+		s.limiter.AllowN(now, 1)
+		if now.Sub(s.lastFailure) >= l.config.LockoutDuration {
+			s.failures = 0
+		}
+		s.lastFailure = now
+		s.failures++
+		if s.failures >= l.config.MaxFailures {
+			s.failures = 0
+			s.lockedUntil = now.Add(l.config.LockoutDuration)
+		}
+	}
+}
+
+// recordSuccess resets the failures of the credential among keys. Those of the
+// client IP are kept until it has not failed for LockoutDuration, so that a
+// client holding one valid token cannot interleave it with guesses to avoid the
+// lockout.
+func (l *RateLimiter) recordSuccess(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, key := range keys {
+		if strings.HasPrefix(key, tokenKeyPrefix) {
+			l.state(key, now).failures = 0
+		}
+	}
+}
+
+func (l *RateLimiter) state(key string, now time.Time) *attemptState {
+	s, ok := l.attempts[key]
+	if !ok {
+		s = &attemptState{limiter: rate.NewLimiter(l.config.Rate, l.config.Burst)}
+		l.attempts[key] = s
+	}
+	s.lastSeen = now
+	return s
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, s := range l.attempts {
+		if now.Sub(s.lastSeen) >= l.config.LockoutDuration && !now.Before(s.lockedUntil) {
+			delete(l.attempts, key)
+		}
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	http.Error(w, "", http.StatusTooManyRequests)
+}
@@ -0,0 +1,249 @@
+package challenge05
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type rateLimitTest struct {
+	handler http.Handler
+	now     time.Time
+}
+
+func newRateLimitTest(config RateLimitConfig) *rateLimitTest {
+	validator := NewStaticTokenValidator(map[string]Principal{"valid-token": {ID: "alice"}})
+	return newRateLimitTestWith(config, validator)
+}
+
+func newRateLimitTestWith(config RateLimitConfig, validator TokenValidator) *rateLimitTest {
+	rt := &rateLimitTest{now: time.Unix(1_700_000_000, 0)}
+	limiter := NewRateLimiter(config)
+	limiter.now = func() time.Time { return rt.now }
+	middleware := NewAuthMiddleware(validator, WithRateLimiter(limiter))
+	rt.handler = middleware(http.HandlerFunc(secureHandler))
+	return rt
+}
+
+func (rt *rateLimitTest) do(
+	t *testing.T,
+	ip, token string,
+	wantStatus int,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+	req.RemoteAddr = ip + ":1234"
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	}
+	rr := httptest.NewRecorder()
+	rt.handler.ServeHTTP(rr, req)
+	if rr.Code != wantStatus {
+		t.Fatalf("Expected status %d, got %d", wantStatus, rr.Code)
+	}
+	return rr
+}
+
+func TestRateLimit(t *testing.T) {
+	rt := newRateLimitTest(RateLimitConfig{Rate: 1, Burst: 2, MaxFailures: 100})
+
+	// Successful attempts are not limited.
+	for range 5 {
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+	}
+
+	rt.do(t, "10.0.0.1", "guess-1", http.StatusUnauthorized)
+	rt.do(t, "10.0.0.1", "guess-1", http.StatusUnauthorized)
+	rr := rt.do(t, "10.0.0.1", "valid-token", http.StatusTooManyRequests)
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After %q, got %q", "1", got)
+	}
+
+	// The failed token is throttled for other clients as well.
+	rt.do(t, "10.0.0.2", "guess-1", http.StatusTooManyRequests)
+	// A rejected attempt does not consume from the client's bucket.
+	rt.do(t, "10.0.0.2", "guess-2", http.StatusUnauthorized)
+	rt.do(t, "10.0.0.2", "valid-token", http.StatusOK)
+
+	rt.now = rt.now.Add(time.Second)
+	rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+}
+
+// newTestBcryptKeys returns a validator of the API key "ci.s3cret".
+func newTestBcryptKeys(t *testing.T) *BcryptKeyValidator {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := LoadBcryptKeys(strings.NewReader("ci " + string(hash)))
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	return v
+}
+
+func TestRateLimiterTokenKey(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{})
+	static := NewStaticTokenValidator(nil)
+	bcryptKeys := newTestBcryptKeys(t)
+	now := time.Unix(1_700_000_000, 0)
+	store := newTestAPIKeyStore(t, &now)
+	id := "0b7e3a4c-2f7d-4e8a-9c1b-5d6e7f8a9b0c"
+
+	// JWTs of different users share their leading bytes.
+	alice := "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.c2ln"
+	bob := "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJib2IifQ.c2ln"
+	if limiter.tokenKey(alice, static) == limiter.tokenKey(bob, static) {
+		t.Error("Expected tokens with a common prefix to have different keys")
+	}
+
+	testCases := []struct {
+		name      string
+		token     string
+		validator TokenValidator
+		want      string
+	}{
+		{name: "Bcrypt key", token: "alice.s3cret", validator: bcryptKeys, want: "alice"},
+		{
+			name:      "Validator chain",
+			token:     "alice.guess",
+			validator: ValidatorChain{static, bcryptKeys},
+			want:      "alice",
+		},
+		{name: "Stored API key", token: id + ".secret", validator: store, want: id},
+		{
+			name:      "Not a stored API key",
+			token:     "alice.s3cret",
+			validator: store,
+			want:      hashToken("alice.s3cret"),
+		},
+		{
+			name:      "Static token",
+			token:     "alice.s3cret",
+			validator: static,
+			want:      hashToken("alice.s3cret"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := limiter.tokenKey(tc.token, tc.validator); got != tc.want {
+				t.Errorf("Expected key %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	custom := NewRateLimiter(RateLimitConfig{TokenKey: strings.ToUpper})
+	if got := custom.tokenKey("alice.s3cret", bcryptKeys); got != "ALICE.S3CRET" {
+		t.Errorf("Expected the configured key, got %q", got)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	config := RateLimitConfig{
+		Rate:            100,
+		Burst:           100,
+		MaxFailures:     3,
+		LockoutDuration: time.Minute,
+	}
+
+	t.Run("Client IP", func(t *testing.T) {
+		rt := newRateLimitTest(config)
+		for _, guess := range []string{"guess-1", "guess-2", "guess-3"} {
+			rt.do(t, "10.0.0.1", guess, http.StatusUnauthorized)
+		}
+		rr := rt.do(t, "10.0.0.1", "valid-token", http.StatusTooManyRequests)
+		if got := rr.Header().Get("Retry-After"); got != "60" {
+			t.Errorf("Expected Retry-After %q, got %q", "60", got)
+		}
+		rt.do(t, "10.0.0.2", "valid-token", http.StatusOK)
+
+		rt.now = rt.now.Add(time.Minute)
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+	})
+
+	t.Run("Token", func(t *testing.T) {
+		rt := newRateLimitTest(config)
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			rt.do(t, ip, "guess", http.StatusUnauthorized)
+		}
+		rt.do(t, "10.0.0.4", "guess", http.StatusTooManyRequests)
+		rt.do(t, "10.0.0.4", "valid-token", http.StatusOK)
+	})
+
+	t.Run("Bcrypt key across IPs", func(t *testing.T) {
+		rt := newRateLimitTestWith(config, newTestBcryptKeys(t))
+		for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			rt.do(t, ip, "ci.guess-"+strconv.Itoa(i), http.StatusUnauthorized)
+		}
+		// Even the right secret is locked out with the key.
+		rt.do(t, "10.0.0.4", "ci.s3cret", http.StatusTooManyRequests)
+		rt.do(t, "10.0.0.4", "cd.guess", http.StatusUnauthorized)
+
+		rt.now = rt.now.Add(time.Minute)
+		rt.do(t, "10.0.0.4", "ci.s3cret", http.StatusOK)
+	})
+
+	t.Run("Client IP failures expire", func(t *testing.T) {
+		rt := newRateLimitTest(config)
+		rt.do(t, "10.0.0.1", "guess-1", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "guess-2", http.StatusUnauthorized)
+		rt.now = rt.now.Add(time.Minute)
+		rt.do(t, "10.0.0.1", "guess-3", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "guess-4", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+		rt.do(t, "10.0.0.1", "guess-5", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusTooManyRequests)
+	})
+
+	t.Run("Success resets token failures only", func(t *testing.T) {
+		// Tokens are keyed by what precedes their dash, as an ID.
+		config := config
+		config.TokenKey = func(token string) string {
+			id, _, _ := strings.Cut(token, "-")
+			return id
+		}
+		rt := newRateLimitTest(config)
+		rt.do(t, "10.0.0.1", "valid-guess", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "valid-guess", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+		rt.do(t, "10.0.0.2", "valid-guess", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.2", "valid-guess", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.3", "valid-token", http.StatusOK)
+
+		// The third failure from 10.0.0.1 locks it out.
+		rt.do(t, "10.0.0.1", "other-guess", http.StatusUnauthorized)
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusTooManyRequests)
+	})
+
+	t.Run("Missing token is not a failure", func(t *testing.T) {
+		rt := newRateLimitTest(config)
+		for range 5 {
+			rt.do(t, "10.0.0.1", "", http.StatusUnauthorized)
+		}
+		rt.do(t, "10.0.0.1", "valid-token", http.StatusOK)
+	})
+}
+
+func TestRateLimiterForgetsIdleKeys(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{LockoutDuration: time.Minute})
+	now := time.Unix(1_700_000_000, 0)
+	limiter.now = func() time.Time { return now }
+
+	limiter.recordFailure([]string{"ip:10.0.0.1"})
+	now = now.Add(2 * time.Minute)
+	limiter.admit([]string{"ip:10.0.0.2"})
+	if _, ok := limiter.attempts["ip:10.0.0.1"]; ok {
+		t.Error("Expected idle key to be forgotten")
+	}
+	// Admitting an attempt keeps no state for keys without failures.
+	if len(limiter.attempts) != 0 {
+		t.Errorf("Expected no keys, got %d", len(limiter.attempts))
+	}
+}
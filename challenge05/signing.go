@@ -0,0 +1,333 @@
+package challenge05
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request signing scheme, loosely modelled on AWS Signature Version 4:
+//
+//	Authorization: HMAC-SHA256 Credential=<key ID>, SignedHeaders=host;x-date;x-nonce, Signature=<hex>
+//	X-Date: 20060102T150405Z
+//	X-Nonce: <random hex>
+//
+// The signature is the HMAC-SHA256, under the key's secret, of
+//
+//	HMAC-SHA256\n<X-Date>\n<hex SHA-256 of the canonical request>
+//
+// where the canonical request lists the method, escaped path, sorted query,
+// signed headers and hex SHA-256 of the body, one per line. The nonce tells
+// apart identical requests sent within the same second, so that only replays
+// are rejected.
+const (
+	signatureAlgorithm = "HMAC-SHA256"
+	signatureDateFmt   = "20060102T150405Z"
+	dateHeader         = "X-Date"
+	nonceHeader        = "X-Nonce"
+	nonceBytes         = 16
+)
+
+// Default limits of a signature middleware, used for zero SignatureConfig fields.
+const (
+	defaultSignatureWindow = 5 * time.Minute
+	defaultMaxSignedBody   = 1 << 20
+)
+
+// ErrInvalidSignature is the error of a request whose signature is not valid.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SigningKey is a secret shared with a client that signs its requests.
+type SigningKey struct {
+	Secret []byte
+	// Principal is who the requests signed with the key authenticate as.
+	Principal Principal
+}
+
+// SignatureConfig configures a middleware created with NewSignatureMiddleware.
+// Zero fields take the defaults.
+type SignatureConfig struct {
+	// Keys holds the signing keys by key ID.
+	Keys map[string]SigningKey
+	// SignedHeaders lists headers that requests must sign, in addition to Host,
+	// X-Date and X-Nonce.
+	SignedHeaders []string
+	// Window is how far the X-Date of a request may be from the current time.
+	// Nonces are remembered for that long to reject replays. Defaults to 5
+	// minutes.
+	Window time.Duration
+	// MaxBodyBytes bounds the size of the request bodies. Defaults to 1 MiB.
+	MaxBodyBytes int64
+}
+
+func (c SignatureConfig) withDefaults() SignatureConfig {
+	if c.Window <= 0 {
+		c.Window = defaultSignatureWindow
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaultMaxSignedBody
+	}
+	return c
+}
+
+type signatureVerifier struct {
+	config SignatureConfig
+	now    func() time.Time
+	mu     sync.Mutex
+	// seen maps the key IDs and nonces of accepted requests to when they can
+	// be forgotten, because their X-Date is out of the window.
+	seen      map[nonceKey]time.Time
+	lastSweep time.Time
+}
+
+// nonceKey identifies a request by the key that signed it and its nonce.
+type nonceKey struct {
+	keyID string
+	nonce string
+}
+
+// NewSignatureMiddleware returns a middleware that authenticates requests by
+// their HMAC signature. It responds with 401 Unauthorized if the signature is
+// missing, invalid, outside of the time window or a replay, and otherwise calls
+// the next handler with the principal of the signing key stored in the request
+// context.
+func NewSignatureMiddleware(config SignatureConfig) func(http.Handler) http.Handler {
+	v := &signatureVerifier{
+		config: config.withDefaults(),
+		now:    time.Now,
+		seen:   make(map[nonceKey]time.Time),
+	}
+	return v.middleware
+}
+
+func (v *signatureVerifier) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := v.verify(w, r)
+		if err != nil {
+			auditDecision(r.Context(), "", DecisionDeny, err.Error())
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "", http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", signatureAlgorithm)
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		auditDecision(r.Context(), principal.ID, DecisionAllow, "valid signature")
+		ctx := context.WithValue(r.Context(), principalKey, principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verify checks the signature of r, and returns the principal of its key. It
+// replaces the body of r, which it reads to hash.
+func (v *signatureVerifier) verify(w http.ResponseWriter, r *http.Request) (Principal, error) {
+	auth, err := parseSignatureAuth(r.Header.Get("Authorization"))
+	if err != nil {
+		return Principal{}, err
+	}
+	key, ok := v.config.Keys[auth.keyID]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, auth.keyID)
+	}
+	for _, h := range append([]string{"host", "x-date", "x-nonce"}, v.config.SignedHeaders...) {
+		if !slices.Contains(auth.signedHeaders, strings.ToLower(h)) {
+			return Principal{}, fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, h)
+		}
+	}
+	date, err := time.Parse(signatureDateFmt, r.Header.Get(dateHeader))
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid %s", ErrInvalidSignature, dateHeader)
+	}
+	now := v.now()
+	if date.Before(now.Add(-v.config.Window)) || date.After(now.Add(v.config.Window)) {
+		return Principal{}, fmt.Errorf("%w: %s out of window", ErrInvalidSignature, dateHeader)
+	}
+	nonce := r.Header.Get(nonceHeader)
+	if nonce == "" {
+		return Principal{}, fmt.Errorf("%w: missing %s", ErrInvalidSignature, nonceHeader)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, v.config.MaxBodyBytes))
+	if err != nil {
+		return Principal{}, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	want := signRequest(key.Secret, r, r.Host, auth.signedHeaders, body, date)
+	if !hmac.Equal(auth.signature, want) {
+		return Principal{}, fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	if !v.remember(nonceKey{keyID: auth.keyID, nonce: nonce}, date.Add(v.config.Window), now) {
+		return Principal{}, fmt.Errorf("%w: replayed request", ErrInvalidSignature)
+	}
+	return key.Principal, nil
+}
+
+// remember records a nonce until forgetAt, and returns false if it was already
+// recorded.
+func (v *signatureVerifier) remember(key nonceKey, forgetAt, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.lastSweep) >= v.config.Window {
+		v.lastSweep = now
+		for k, t := range v.seen {
+			if now.After(t) {
+				delete(v.seen, k)
+			}
+		}
+	}
+	if _, ok := v.seen[key]; ok {
+		return false
+	}
+	v.seen[key] = forgetAt
+	return true
+}
+
+type signatureAuth struct {
+	keyID         string
+	signedHeaders []string
+	signature     []byte
+}
+
+func parseSignatureAuth(header string) (signatureAuth, error) {
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || scheme != signatureAlgorithm {
+		return signatureAuth{}, fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	var auth signatureAuth
+	for param := range strings.SplitSeq(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "Credential":
+			auth.keyID = value
+		case "SignedHeaders":
+			auth.signedHeaders = strings.Split(strings.ToLower(value), ";")
+		case "Signature":
+			auth.signature, _ = hex.DecodeString(value)
+		}
+	}
+	if auth.keyID == "" || auth.signedHeaders == nil || auth.signature == nil {
+		return signatureAuth{}, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	return auth, nil
+}
+
+// signRequest returns the signature of r, with the given host, signed headers
+// and body, at date.
+func signRequest(
+	secret []byte,
+	r *http.Request,
+	host string,
+	signedHeaders []string,
+	body []byte,
+	date time.Time,
+) []byte {
+	var canonical strings.Builder
+	canonical.WriteString(r.Method + "\n")
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical.WriteString(path + "\n")
+	// Encode sorts by key.
+	canonical.WriteString(r.URL.Query().Encode() + "\n")
+	for _, name := range signedHeaders {
+		value := host
+		if name != "host" {
+			value = strings.Join(r.Header.Values(name), ",")
+		}
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonical.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	bodyHash := sha256.Sum256(body)
+	canonical.WriteString(hex.EncodeToString(bodyHash[:]))
+
+	canonicalHash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := signatureAlgorithm + "\n" + date.UTC().Format(signatureDateFmt) + "\n" +
+		hex.EncodeToString(canonicalHash[:])
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	return mac.Sum(nil)
+}
+
+// SigningTransport is an http.RoundTripper that signs requests for a
+// middleware created with NewSignatureMiddleware.
+type SigningTransport struct {
+	KeyID  string
+	Secret []byte
+	// SignedHeaders lists headers to sign, in addition to Host, X-Date and
+	// X-Nonce.
+	SignedHeaders []string
+	// Base sends the signed requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	now func() time.Time
+}
+
+func (t *SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	date := now().UTC().Truncate(time.Second)
+	nonce := make([]byte, nonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// A RoundTripper must not modify the request it is given.
+	signed := req.Clone(req.Context())
+	if body != nil {
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	signed.Header.Set(dateHeader, date.Format(signatureDateFmt))
+	signed.Header.Set(nonceHeader, hex.EncodeToString(nonce))
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	signedHeaders := []string{"host", "x-date", "x-nonce"}
+	for _, h := range t.SignedHeaders {
+		if h = strings.ToLower(h); !slices.Contains(signedHeaders, h) {
+			signedHeaders = append(signedHeaders, h)
+		}
+	}
+	signature := signRequest(t.Secret, signed, host, signedHeaders, body, date)
+	signed.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s, SignedHeaders=%s, Signature=%s",
+		signatureAlgorithm,
+		t.KeyID,
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(signature),
+	))
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
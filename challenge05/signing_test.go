@@ -0,0 +1,244 @@
+package challenge05
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingTransport sends requests with http.DefaultTransport, and keeps the last one.
+type recordingTransport struct {
+	last *http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.last = req
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func setupSigningServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	middleware := NewSignatureMiddleware(SignatureConfig{
+		Keys: map[string]SigningKey{
+			"reports": {Secret: []byte("s3cret"), Principal: Principal{ID: "reports-svc"}},
+		},
+		SignedHeaders: []string{"Content-Type"},
+		MaxBodyBytes:  64,
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, principal.ID+" "+string(body))
+	})
+	server := httptest.NewServer(middleware(handler))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func send(
+	t *testing.T,
+	client *http.Client,
+	method, url, body string,
+	wantStatus int,
+) string {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("Expected status %d, got %d", wantStatus, resp.StatusCode)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return string(data)
+}
+
+func TestSignedRequests(t *testing.T) {
+	server := setupSigningServer(t)
+	transport := &SigningTransport{
+		KeyID:         "reports",
+		Secret:        []byte("s3cret"),
+		SignedHeaders: []string{"Content-Type"},
+	}
+	client := &http.Client{Transport: transport}
+
+	t.Run("Valid", func(t *testing.T) {
+		got := send(
+			t,
+			client,
+			http.MethodPost,
+			server.URL+"/jobs?b=2&a=1",
+			"payload",
+			http.StatusOK,
+		)
+		if got != "reports-svc payload" {
+			t.Errorf("Expected %q, got %q", "reports-svc payload", got)
+		}
+		send(t, client, http.MethodGet, server.URL, "", http.StatusOK)
+	})
+
+	t.Run("Identical requests", func(t *testing.T) {
+		// The requests are signed within the same second, but with
+		// different nonces.
+		for range 3 {
+			send(t, client, http.MethodGet, server.URL, "", http.StatusOK)
+		}
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		send(t, server.Client(), http.MethodGet, server.URL, "", http.StatusUnauthorized)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		bad := &http.Client{Transport: &SigningTransport{
+			KeyID:         "reports",
+			Secret:        []byte("guess"),
+			SignedHeaders: []string{"Content-Type"},
+		}}
+		send(t, bad, http.MethodGet, server.URL, "", http.StatusUnauthorized)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		bad := &http.Client{Transport: &SigningTransport{
+			KeyID:         "other",
+			Secret:        []byte("s3cret"),
+			SignedHeaders: []string{"Content-Type"},
+		}}
+		send(t, bad, http.MethodGet, server.URL, "", http.StatusUnauthorized)
+	})
+
+	t.Run("Required header not signed", func(t *testing.T) {
+		bad := &http.Client{
+			Transport: &SigningTransport{KeyID: "reports", Secret: []byte("s3cret")},
+		}
+		send(t, bad, http.MethodGet, server.URL, "", http.StatusUnauthorized)
+	})
+
+	t.Run("Stale date", func(t *testing.T) {
+		stale := &http.Client{Transport: &SigningTransport{
+			KeyID:         "reports",
+			Secret:        []byte("s3cret"),
+			SignedHeaders: []string{"Content-Type"},
+			now:           func() time.Time { return time.Now().Add(-10 * time.Minute) },
+		}}
+		send(t, stale, http.MethodGet, server.URL, "", http.StatusUnauthorized)
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		send(
+			t,
+			client,
+			http.MethodPost,
+			server.URL,
+			strings.Repeat("x", 65),
+			http.StatusRequestEntityTooLarge,
+		)
+	})
+}
+
+func TestSignedRequestTampering(t *testing.T) {
+	server := setupSigningServer(t)
+	recorder := &recordingTransport{}
+	client := &http.Client{Transport: &SigningTransport{
+		KeyID:         "reports",
+		Secret:        []byte("s3cret"),
+		SignedHeaders: []string{"Content-Type"},
+		Base:          recorder,
+	}}
+	send(t, client, http.MethodPost, server.URL+"/jobs", "payload", http.StatusOK)
+	signed := recorder.last
+
+	resend := func(t *testing.T, modify func(req *http.Request), body string, wantStatus int) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(
+			t.Context(),
+			signed.Method,
+			signed.URL.String(),
+			strings.NewReader(body),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = signed.Header.Clone()
+		modify(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Errorf("Expected status %d, got %d", wantStatus, resp.StatusCode)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(req *http.Request)
+		body   string
+	}{
+		{name: "Replay", modify: func(*http.Request) {}, body: "payload"},
+		{name: "Body", modify: func(*http.Request) {}, body: "payloaf"},
+		{
+			name:   "Path",
+			modify: func(req *http.Request) { req.URL.Path = "/admin" },
+			body:   "payload",
+		},
+		{
+			name:   "Signed header",
+			modify: func(req *http.Request) { req.Header.Set("Content-Type", "text/html") },
+			body:   "payload",
+		},
+		{
+			name:   "Nonce",
+			modify: func(req *http.Request) { req.Header.Set("X-Nonce", "0123456789abcdef") },
+			body:   "payload",
+		},
+		{
+			name:   "Missing nonce",
+			modify: func(req *http.Request) { req.Header.Del("X-Nonce") },
+			body:   "payload",
+		},
+		{
+			name:   "Method",
+			modify: func(req *http.Request) { req.Method = http.MethodPut },
+			body:   "payload",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resend(t, tc.modify, tc.body, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestSigningTransportDoesNotModifyRequest(t *testing.T) {
+	server := setupSigningServer(t)
+	client := &http.Client{Transport: &SigningTransport{
+		KeyID:         "reports",
+		Secret:        []byte("s3cret"),
+		SignedHeaders: []string{"Content-Type"},
+	}}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if req.Header.Get("Authorization") != "" || req.Header.Get("X-Date") != "" ||
+		req.Header.Get("X-Nonce") != "" {
+		t.Errorf("Expected the original request to be unchanged, got headers %v", req.Header)
+	}
+}
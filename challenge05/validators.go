@@ -0,0 +1,228 @@
+package challenge05
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidToken is returned by a TokenValidator for a token it does not accept.
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenValidator authenticates callers by the token they present.
+type TokenValidator interface {
+	// ValidateToken returns the principal that token belongs to, or an error
+	// wrapping ErrInvalidToken if the token is not valid. Any other error is
+	// a failure to validate the token.
+	ValidateToken(ctx context.Context, token string) (Principal, error)
+}
+
+// CredentialKeyer is implemented by the token validators that can tell which
+// credential a token presents without validating it, such as the ID of an API
+// key. A RateLimiter counts the failed attempts with a credential together, so
+// that guesses of its secret are limited across clients.
+type CredentialKeyer interface {
+	// CredentialKey returns the key of the credential that token presents, or
+	// false if the token does not identify one.
+	CredentialKey(token string) (string, bool)
+}
+
+// ValidatorChain accepts the tokens that any of its validators accepts, trying
+// them in order.
+type ValidatorChain []TokenValidator
+
+func (c ValidatorChain) ValidateToken(ctx context.Context, token string) (Principal, error) {
+	for _, v := range c {
+		principal, err := v.ValidateToken(ctx, token)
+		if !errors.Is(err, ErrInvalidToken) {
+			return principal, err
+		}
+	}
+	return Principal{}, ErrInvalidToken
+}
+
+// CredentialKey returns the key that the first of the validators that can
+// tell returns for token.
+func (c ValidatorChain) CredentialKey(token string) (string, bool) {
+	for _, v := range c {
+		if keyer, ok := v.(CredentialKeyer); ok {
+			if key, ok := keyer.CredentialKey(token); ok {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// StaticTokenValidator accepts a fixed set of tokens.
+type StaticTokenValidator struct {
+	tokens []staticToken
+}
+
+type staticToken struct {
+	hash      [sha256.Size]byte
+	principal Principal
+}
+
+// NewStaticTokenValidator returns a validator that accepts the keys of tokens,
+// each authenticating the principal it maps to.
+func NewStaticTokenValidator(tokens map[string]Principal) *StaticTokenValidator {
+	v := &StaticTokenValidator{tokens: make([]staticToken, 0, len(tokens))}
+	for token, principal := range tokens {
+		v.tokens = append(v.tokens, staticToken{sha256.Sum256([]byte(token)), principal})
+	}
+	return v
+}
+
+func (v *StaticTokenValidator) ValidateToken(_ context.Context, token string) (Principal, error) {
+	// Compare fixed-size hashes against every token, so that the time taken
+	// reveals neither the tokens' lengths nor which of them matched.
+	hash := sha256.Sum256([]byte(token))
+	var principal Principal
+	found := 0
+	for _, t := range v.tokens {
+		match := subtle.ConstantTimeCompare(hash[:], t.hash[:])
+		if match == 1 {
+			principal = t.principal
+		}
+		found |= match
+	}
+	if found == 0 {
+		return Principal{}, ErrInvalidToken
+	}
+	return principal, nil
+}
+
+// HMACTokenValidator accepts tokens issued by its Sign method, which carry the
+// principal ID and expiry time, authenticated by an HMAC-SHA256 signature.
+type HMACTokenValidator struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewHMACTokenValidator returns a validator for tokens signed with key.
+func NewHMACTokenValidator(key []byte) *HMACTokenValidator {
+	return &HMACTokenValidator{key: key, now: time.Now}
+}
+
+// Sign issues a token for principalID that is valid until expiresAt.
+func (v *HMACTokenValidator) Sign(principalID string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(principalID)) +
+		"." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(v.mac(payload))
+}
+
+func (v *HMACTokenValidator) ValidateToken(_ context.Context, token string) (Principal, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return Principal{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	payload := token[:i]
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(sig, v.mac(payload)) {
+		return Principal{}, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	encodedID, expiry, ok := strings.Cut(payload, ".")
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	id, err := base64.RawURLEncoding.DecodeString(encodedID)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed principal", ErrInvalidToken)
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed expiry", ErrInvalidToken)
+	}
+	if !v.now().Before(time.Unix(expiresAt, 0)) {
+		return Principal{}, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	return Principal{ID: string(id)}, nil
+}
+
+func (v *HMACTokenValidator) mac(payload string) []byte {
+	h := hmac.New(sha256.New, v.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// BcryptKeyValidator accepts API keys of the form "<id>.<secret>", where the
+// bcrypt hash of the secret is known for id. The principal ID is the key ID.
+type BcryptKeyValidator struct {
+	hashes map[string][]byte
+}
+
+// LoadBcryptKeyFile loads a BcryptKeyValidator from the file at path. See
+// LoadBcryptKeys for the format.
+func LoadBcryptKeyFile(path string) (*BcryptKeyValidator, error) {
+	f, err := os.Open(path) //nolint:gosec // The path is trusted configuration.
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return LoadBcryptKeys(f)
+}
+
+// LoadBcryptKeys loads a BcryptKeyValidator from r, which has a
+// "<id> <bcrypt hash>" pair per line. Blank lines and lines starting with '#'
+// are ignored.
+func LoadBcryptKeys(r io.Reader) (*BcryptKeyValidator, error) {
+	v := &BcryptKeyValidator{hashes: make(map[string][]byte)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected an ID and a hash", line)
+		}
+		id, hash := fields[0], []byte(fields[1])
+		if strings.Contains(id, ".") {
+			return nil, fmt.Errorf("line %d: key ID %q contains '.'", line, id)
+		}
+		if _, err := bcrypt.Cost(hash); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, ok := v.hashes[id]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key ID %q", line, id)
+		}
+		v.hashes[id] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *BcryptKeyValidator) ValidateToken(_ context.Context, token string) (Principal, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: malformed API key", ErrInvalidToken)
+	}
+	hash, ok := v.hashes[id]
+	if !ok || bcrypt.CompareHashAndPassword(hash, []byte(secret)) != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	return Principal{ID: id}, nil
+}
+
+// CredentialKey returns the ID of the API key that token presents.
+func (v *BcryptKeyValidator) CredentialKey(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	return id, ok && id != ""
+}
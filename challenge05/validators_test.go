@@ -0,0 +1,177 @@
+package challenge05
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func assertValid(t *testing.T, v TokenValidator, token, wantID string) {
+	t.Helper()
+	principal, err := v.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if principal.ID != wantID {
+		t.Errorf("Expected principal %q, got %q", wantID, principal.ID)
+	}
+}
+
+func assertInvalid(t *testing.T, v TokenValidator, token string) {
+	t.Helper()
+	if _, err := v.ValidateToken(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for %q, got %v", token, err)
+	}
+}
+
+func TestStaticTokenValidator(t *testing.T) {
+	v := NewStaticTokenValidator(map[string]Principal{"a": {ID: "alice"}, "b": {ID: "bob"}})
+	assertValid(t, v, "a", "alice")
+	assertValid(t, v, "b", "bob")
+	for _, token := range []string{"", "c", "ab", "A"} {
+		assertInvalid(t, v, token)
+	}
+}
+
+func TestHMACTokenValidator(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := NewHMACTokenValidator([]byte("key"))
+	v.now = func() time.Time { return now }
+
+	token := v.Sign("svc.reports", now.Add(time.Minute))
+	assertValid(t, v, token, "svc.reports")
+
+	other := NewHMACTokenValidator([]byte("other key"))
+	other.now = v.now
+
+	testCases := []struct {
+		name  string
+		v     *HMACTokenValidator
+		token string
+	}{
+		{name: "Expired", v: v, token: v.Sign("svc", now)},
+		{name: "Wrong key", v: other, token: token},
+		{name: "Tampered payload", v: v, token: "x" + token},
+		{name: "Tampered signature", v: v, token: token[:len(token)-2] + "AA"},
+		{name: "Malformed", v: v, token: "svc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertInvalid(t, tc.v, tc.token)
+		})
+	}
+}
+
+func TestBcryptKeyValidator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys")
+	data := "# API keys\n\nci " + string(hash) + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := LoadBcryptKeyFile(path)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	assertValid(t, v, "ci.s3cret", "ci")
+	for _, token := range []string{"ci.wrong", "cd.s3cret", "s3cret", ""} {
+		assertInvalid(t, v, token)
+	}
+
+	t.Run("Invalid files", func(t *testing.T) {
+		for _, data := range []string{
+			"ci",
+			"ci not-a-hash",
+			"c.i " + string(hash),
+			"ci " + string(hash) + "\nci " + string(hash),
+		} {
+			if _, err := LoadBcryptKeys(strings.NewReader(data)); err == nil {
+				t.Errorf("Expected error for %q", data)
+			}
+		}
+	})
+}
+
+type errValidator struct{}
+
+func (errValidator) ValidateToken(context.Context, string) (Principal, error) {
+	return Principal{}, errors.New("backend down")
+}
+
+func TestNewAuthMiddleware(t *testing.T) {
+	echoPrincipal := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			t.Error("Expected a principal in the request context")
+		}
+		_, _ = w.Write([]byte(principal.ID))
+	})
+
+	testCases := []struct {
+		name       string
+		validator  TokenValidator
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Valid token",
+			validator:  NewStaticTokenValidator(map[string]Principal{"t": {ID: "alice"}}),
+			token:      "t",
+			wantStatus: http.StatusOK,
+			wantBody:   "alice",
+		},
+		{
+			name:       "Invalid token",
+			validator:  NewStaticTokenValidator(map[string]Principal{"t": {ID: "alice"}}),
+			token:      "u",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Validator failure",
+			validator:  errValidator{},
+			token:      "t",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Auth-Token", tc.token)
+			rr := httptest.NewRecorder()
+			NewAuthMiddleware(tc.validator)(echoPrincipal).ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != tc.wantBody {
+				t.Errorf("Expected body %q, got %q", tc.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestValidatorChain(t *testing.T) {
+	chain := ValidatorChain{
+		NewStaticTokenValidator(map[string]Principal{"a": {ID: "alice"}}),
+		NewStaticTokenValidator(map[string]Principal{"a": {ID: "other"}, "b": {ID: "bob"}}),
+	}
+	assertValid(t, chain, "a", "alice")
+	assertValid(t, chain, "b", "bob")
+	assertInvalid(t, chain, "c")
+	assertInvalid(t, ValidatorChain{}, "a")
+}
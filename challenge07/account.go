@@ -4,6 +4,7 @@ package challenge07
 import (
 	"fmt"
 	"sync"
+	"time"
 	// Add any other necessary imports
 )
 
@@ -11,16 +12,29 @@ import (
 type BankAccount struct {
 	ID         string
 	Owner      string
-	Balance    float64
-	MinBalance float64
+	Balance    Money
+	MinBalance Money
+	currency   Currency
+	limits     Limits
+	now        func() time.Time
+	ledger     []LedgerEntry
+	holds      map[int64]Hold
+	lastHold   int64
 	mu         sync.Mutex
 }
 
 // Constants for account operations
 const (
-	MaxTransactionAmount = 10000.0 // Example limit for deposits/withdrawals
+	// MaxTransactionAmount is the default limit of deposits and withdrawals,
+	// in major units of the account currency.
+	MaxTransactionAmount = 10000
 )
 
+// maxTransaction returns MaxTransactionAmount in currency.
+func maxTransaction(currency Currency) Money {
+	return NewMoney(MaxTransactionAmount*currency.scale(), currency)
+}
+
 // Custom error types
 
 // AccountError is a general error type for bank account operations.
@@ -37,16 +51,28 @@ func (e *AccountError) Error() string {
 	)
 }
 
-// InsufficientFundsError occurs when a withdrawal or transfer would bring the balance below minimum.
+// InsufficientFundsError occurs when a withdrawal, transfer or hold would bring the available
+// balance, which excludes the amounts on hold, below minimum.
 type InsufficientFundsError struct {
 	accountID  string
-	amount     float64
-	minBalance float64
+	amount     Money
+	minBalance Money
+	held       Money
 }
 
 func (e *InsufficientFundsError) Error() string {
+	if !e.held.IsZero() {
+		return fmt.Sprintf(
+			"account ID: %s, transaction of amount %s would bring the available balance "+
+				"below the minimum %s, with %s on hold",
+			e.accountID,
+			e.amount,
+			e.minBalance,
+			e.held,
+		)
+	}
 	return fmt.Sprintf(
-		"account ID: %s, transaction of amount %f would bring the balance below the minimum %f",
+		"account ID: %s, transaction of amount %s would bring the balance below the minimum %s",
 		e.accountID,
 		e.amount,
 		e.minBalance,
@@ -65,34 +91,98 @@ func (e *NegativeAmountError) Error() string {
 	)
 }
 
-// ExceedsLimitError occurs when a deposit or withdrawal amount exceeds the defined limit.
+// ExceedsLimitError occurs when a deposit or withdrawal amount exceeds a limit of the account.
 type ExceedsLimitError struct {
 	accountID string
-	amount    float64
+	kind      LimitKind
+	amount    Money
+	limit     Money
 }
 
 func (e *ExceedsLimitError) Error() string {
 	return fmt.Sprintf(
-		"account ID: %s, transaction of amount %f exceeds the limit %f",
+		"account ID: %s, transaction of amount %s exceeds the %s limit %s",
 		e.accountID,
 		e.amount,
-		MaxTransactionAmount,
+		e.kind,
+		e.limit,
 	)
 }
 
-// NewBankAccount creates a new bank account with the given parameters.
+// Kind returns the kind of the limit that the transaction exceeded.
+func (e *ExceedsLimitError) Kind() LimitKind {
+	return e.kind
+}
+
+// Limit returns the limit that the transaction exceeded.
+func (e *ExceedsLimitError) Limit() Money {
+	return e.limit
+}
+
+// Amount returns the amount of the transaction.
+func (e *ExceedsLimitError) Amount() Money {
+	return e.amount
+}
+
+// CurrencyMismatchError occurs when an amount or account is not in the currency of the account.
+type CurrencyMismatchError struct {
+	accountID string
+	currency  Currency
+	want      Currency
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf(
+		"account ID: %s, currency %s does not match the account currency %s",
+		e.accountID,
+		e.currency,
+		e.want,
+	)
+}
+
+// NewBankAccount creates a new bank account with the given parameters, in the
+// currency of initialBalance. A zero minBalance means no minimum balance, and
+// opts configure the limits of the account.
 // It returns an error if any of the parameters are invalid.
-func NewBankAccount(id, owner string, initialBalance, minBalance float64) (*BankAccount, error) {
+func NewBankAccount(
+	id, owner string,
+	initialBalance, minBalance Money,
+	opts ...AccountOption,
+) (*BankAccount, error) {
+	return newBankAccount(id, owner, initialBalance, minBalance, time.Now, opts...)
+}
+
+// newBankAccount is NewBankAccount with the clock of the account.
+func newBankAccount(
+	id, owner string,
+	initialBalance, minBalance Money,
+	now func() time.Time,
+	opts ...AccountOption,
+) (*BankAccount, error) {
 	if id == "" {
 		return nil, &AccountError{accountID: id, message: "account ID is blank"}
 	}
 	if owner == "" {
 		return nil, &AccountError{accountID: id, message: "account owner is blank"}
 	}
-	if initialBalance < 0 || minBalance < 0 {
+	currency := initialBalance.Currency()
+	if !currency.Valid() {
+		return nil, &AccountError{accountID: id, message: "account currency is invalid"}
+	}
+	if minBalance == (Money{}) {
+		minBalance = NewMoney(0, currency)
+	}
+	if minBalance.Currency() != currency {
+		return nil, &CurrencyMismatchError{
+			accountID: id,
+			currency:  minBalance.Currency(),
+			want:      currency,
+		}
+	}
+	if initialBalance.IsNegative() || minBalance.IsNegative() {
 		return nil, &NegativeAmountError{accountID: id}
 	}
-	if initialBalance < minBalance {
+	if initialBalance.Cmp(minBalance) < 0 {
 		return nil, &InsufficientFundsError{
 			accountID:  id,
 			amount:     initialBalance,
@@ -100,81 +190,228 @@ func NewBankAccount(id, owner string, initialBalance, minBalance float64) (*Bank
 		}
 	}
 
-	return &BankAccount{
+	a := &BankAccount{
 		ID:         id,
 		Owner:      owner,
 		Balance:    initialBalance,
 		MinBalance: minBalance,
-	}, nil
+		currency:   currency,
+		now:        now,
+	}
+	var limits Limits
+	for _, opt := range opts {
+		opt(&limits)
+	}
+	limits, err := a.validateLimits(limits)
+	if err != nil {
+		return nil, err
+	}
+	a.limits = limits
+	a.record(LedgerEntry{Type: EntryOpening, Amount: initialBalance, Time: a.clock()})
+	return a, nil
+}
+
+// Currency returns the currency of the account.
+func (a *BankAccount) Currency() Currency {
+	return a.currency
 }
 
 // Deposit adds the specified amount to the account balance.
-// It returns an error if the amount is invalid or exceeds the transaction limit.
-func (a *BankAccount) Deposit(amount float64) error {
-	if err := validateAmount(a.ID, amount); err != nil {
+// It returns an error if the amount is invalid or exceeds the transaction limit
+// of the account.
+func (a *BankAccount) Deposit(amount Money) error {
+	if err := a.validateAmount(amount); err != nil {
 		return err
 	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	return a.transact(amount)
+	return a.transact(LedgerEntry{Type: EntryDeposit, Amount: amount, Time: a.clock()})
 }
 
 // Withdraw removes the specified amount from the account balance.
-// It returns an error if the amount is invalid, exceeds the transaction limit,
-// or would bring the balance below the minimum required balance.
-func (a *BankAccount) Withdraw(amount float64) error {
+// It returns an error if the amount is invalid, exceeds a limit of the account,
+// or would bring the balance below the minimum required balance and the
+// overdraft of the account.
+func (a *BankAccount) Withdraw(amount Money) error {
 	// Implement withdrawal functionality with proper error handling
-	if err := validateAmount(a.ID, amount); err != nil {
+	if err := a.validateAmount(amount); err != nil {
 		return err
 	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	return a.transact(-amount)
+	return a.transact(LedgerEntry{Type: EntryWithdrawal, Amount: amount.Neg(), Time: a.clock()})
 }
 
-func validateAmount(id string, amount float64) error {
-	if amount < 0 {
-		return &NegativeAmountError{accountID: id}
-	}
-	if amount > MaxTransactionAmount {
-		return &ExceedsLimitError{accountID: id, amount: amount}
-	}
-	return nil
+// Transfer moves the specified amount from this account to the target account.
+// It returns an error if the amount is invalid, exceeds a limit of the source
+// account, or would bring the balance below the minimum required balance and
+// the overdraft of the source account, or if the accounts have different
+// currencies.
+
+//nolint:revive // receiver-naming: `from` is clearer than `a`.
+func (from *BankAccount) Transfer(amount Money, to *BankAccount) error {
+	return from.TransferFX(amount, to, nil)
 }
 
-// Transfer moves the specified amount from this account to the target account.
-// It returns an error if the amount is invalid, exceeds the transaction limit,
-// or would bring the balance below the minimum required balance.
+// TransferFX moves the specified amount from this account to the target account
+// as Transfer does, converting it to the currency of the target account at the
+// rate that rates gives if the currencies differ. It returns an
+// ExchangeRateError if rates has no rate for them.
 
 //nolint:revive // receiver-naming: `from` is clearer than `a`.
-func (from *BankAccount) Transfer(amount float64, to *BankAccount) error {
-	if err := validateAmount(from.ID, amount); err != nil {
+func (from *BankAccount) TransferFX(
+	amount Money,
+	to *BankAccount,
+	rates ExchangeRateProvider,
+) error {
+	// Look up the rate before locking the accounts, since it may be slow.
+	x, err := prepareTransfer(from, to, amount, rates)
+	if err != nil {
 		return err
 	}
+	unlock := lockPair(from, to)
+	defer unlock()
+
+	// The exported methods try to acquire the locks again, and block forever
+	// since `sync.Mutex` is not reentrant. Call the unexported internal function.
+	return transfer(from, to, x)
+}
+
+// prepareTransfer validates a transfer of amount between two accounts, and
+// returns the amounts that leave and reach them. Transfers between currencies
+// need rates.
+func prepareTransfer(
+	from, to *BankAccount,
+	amount Money,
+	rates ExchangeRateProvider,
+) (Exchange, error) {
+	if err := from.validateAmount(amount); err != nil {
+		return Exchange{}, err
+	}
+	if to.currency == from.currency {
+		return Exchange{Source: amount, Destination: amount}, nil
+	}
+	if rates == nil {
+		return Exchange{}, &CurrencyMismatchError{
+			accountID: to.ID,
+			currency:  to.currency,
+			want:      from.currency,
+		}
+	}
+	x, err := exchange(amount, to.currency, rates)
+	if err != nil {
+		return Exchange{}, &ExchangeRateError{
+			accountID: from.ID,
+			from:      from.currency,
+			to:        to.currency,
+			err:       err,
+		}
+	}
+	return x, nil
+}
+
+// lockPair locks two accounts, and returns a function that unlocks them.
+func lockPair(a, b *BankAccount) func() {
 	// Lock accounts in a consistent order to prevent deadlocks.
 	// For example, always lock the account with the smaller ID first.
-	if from.ID < to.ID {
-		from.mu.Lock()
-		to.mu.Lock()
+	if a.ID < b.ID {
+		a.mu.Lock()
+		b.mu.Lock()
 	} else {
-		to.mu.Lock()
-		from.mu.Lock()
+		b.mu.Lock()
+		a.mu.Lock()
+	}
+	return func() {
+		a.mu.Unlock()
+		b.mu.Unlock()
 	}
-	defer from.mu.Unlock()
-	defer to.mu.Unlock()
+}
 
-	// The exported methods try to acquire the locks again, and block forever
-	// since `sync.Mutex` is not reentrant. Call the unexported internal method.
-	if err := from.transact(-amount); err != nil {
+// transfer moves x.Source out of from and x.Destination into to, two locked
+// accounts. The entries of a transfer between currencies record x.
+func transfer(from, to *BankAccount, x Exchange) error {
+	at := from.clock()
+	out := LedgerEntry{
+		Type:         EntryTransferOut,
+		Amount:       x.Source.Neg(),
+		Counterparty: to.ID,
+		Time:         at,
+	}
+	in := LedgerEntry{
+		Type:         EntryTransferIn,
+		Amount:       x.Destination,
+		Counterparty: from.ID,
+		Time:         at,
+	}
+	if from.currency != to.currency {
+		out.Exchange, in.Exchange = x, x
+	}
+	if err := from.transact(out); err != nil {
 		return err
 	}
-	return to.transact(amount)
+	return to.transact(in)
 }
 
-func (a *BankAccount) transact(amount float64) error {
-	if amount < 0 && a.Balance+amount < a.MinBalance {
-		return &InsufficientFundsError{accountID: a.ID, amount: amount, minBalance: a.MinBalance}
+func (a *BankAccount) validateAmount(amount Money) error {
+	if amount.Currency() != a.currency {
+		return &CurrencyMismatchError{
+			accountID: a.ID,
+			currency:  amount.Currency(),
+			want:      a.currency,
+		}
+	}
+	if amount.IsNegative() {
+		return &NegativeAmountError{accountID: a.ID}
+	}
+	return nil
+}
+
+// transact changes the balance by the amount of entry, and records entry in the
+// ledger. It checks entry against the limits of the account and its available
+// balance together, under the lock of the account.
+func (a *BankAccount) transact(entry LedgerEntry) error {
+	if err := a.checkLimits(entry); err != nil {
+		return err
+	}
+	if entry.Amount.IsNegative() {
+		if err := a.checkFunds(entry.Amount, entry.Time); err != nil {
+			return err
+		}
+	}
+	balance, err := a.Balance.CheckedAdd(entry.Amount)
+	if err != nil {
+		return err
+	}
+	a.Balance = balance
+	a.record(entry)
+	return nil
+}
+
+// checkFunds checks that the locked account has the funds for a debit of
+// amount, negative, at the given time, below its available balance down to its
+// minimum balance and overdraft. It returns an error wrapping ErrInvalidMoney
+// if the amounts are out of range.
+func (a *BankAccount) checkFunds(amount Money, at time.Time) error {
+	held := a.held(at)
+	floor, err := a.MinBalance.CheckedSub(a.limits.Overdraft)
+	if err != nil {
+		return err
+	}
+	available, err := a.Balance.CheckedSub(held)
+	if err != nil {
+		return err
+	}
+	after, err := available.CheckedAdd(amount)
+	if err != nil {
+		return err
+	}
+	if after.Cmp(floor) < 0 {
+		return &InsufficientFundsError{
+			accountID:  a.ID,
+			amount:     amount,
+			minBalance: floor,
+			held:       held,
+		}
 	}
-	a.Balance += amount
 	return nil
 }
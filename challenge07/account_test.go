@@ -1,7 +1,9 @@
 package challenge07
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"testing"
@@ -12,8 +14,8 @@ func TestNewBankAccount(t *testing.T) {
 		name           string
 		id             string
 		owner          string
-		initialBalance float64
-		minBalance     float64
+		initialBalance Money
+		minBalance     Money
 		shouldError    bool
 		errorType      string
 	}{
@@ -21,16 +23,16 @@ func TestNewBankAccount(t *testing.T) {
 			name:           "Valid account creation",
 			id:             "ACC001",
 			owner:          "Alice",
-			initialBalance: 1000.0,
-			minBalance:     100.0,
+			initialBalance: usd("1000"),
+			minBalance:     usd("100"),
 			shouldError:    false,
 		},
 		{
 			name:           "Empty ID",
 			id:             "",
 			owner:          "Alice",
-			initialBalance: 1000.0,
-			minBalance:     100.0,
+			initialBalance: usd("1000"),
+			minBalance:     usd("100"),
 			shouldError:    true,
 			errorType:      "AccountError",
 		},
@@ -38,8 +40,8 @@ func TestNewBankAccount(t *testing.T) {
 			name:           "Empty owner",
 			id:             "ACC001",
 			owner:          "",
-			initialBalance: 1000.0,
-			minBalance:     100.0,
+			initialBalance: usd("1000"),
+			minBalance:     usd("100"),
 			shouldError:    true,
 			errorType:      "AccountError",
 		},
@@ -47,8 +49,8 @@ func TestNewBankAccount(t *testing.T) {
 			name:           "Negative initial balance",
 			id:             "ACC001",
 			owner:          "Alice",
-			initialBalance: -100.0,
-			minBalance:     100.0,
+			initialBalance: usd("-100"),
+			minBalance:     usd("100"),
 			shouldError:    true,
 			errorType:      "NegativeAmountError",
 		},
@@ -56,8 +58,8 @@ func TestNewBankAccount(t *testing.T) {
 			name:           "Negative min balance",
 			id:             "ACC001",
 			owner:          "Alice",
-			initialBalance: 1000.0,
-			minBalance:     -100.0,
+			initialBalance: usd("1000"),
+			minBalance:     usd("-100"),
 			shouldError:    true,
 			errorType:      "NegativeAmountError",
 		},
@@ -65,8 +67,8 @@ func TestNewBankAccount(t *testing.T) {
 			name:           "Initial balance less than min balance",
 			id:             "ACC001",
 			owner:          "Alice",
-			initialBalance: 50.0,
-			minBalance:     100.0,
+			initialBalance: usd("50"),
+			minBalance:     usd("100"),
 			shouldError:    true,
 			errorType:      "InsufficientFundsError",
 		},
@@ -106,7 +108,7 @@ func TestNewBankAccount(t *testing.T) {
 
 				if account.Balance != tc.initialBalance {
 					t.Errorf(
-						"Expected Balance %.2f but got %.2f",
+						"Expected Balance %s but got %s",
 						tc.initialBalance,
 						account.Balance,
 					)
@@ -114,7 +116,7 @@ func TestNewBankAccount(t *testing.T) {
 
 				if account.MinBalance != tc.minBalance {
 					t.Errorf(
-						"Expected MinBalance %.2f but got %.2f",
+						"Expected MinBalance %s but got %s",
 						tc.minBalance,
 						account.MinBalance,
 					)
@@ -127,29 +129,29 @@ func TestNewBankAccount(t *testing.T) {
 func TestDeposit(t *testing.T) {
 	testCases := []struct {
 		name        string
-		amount      float64
+		amount      Money
 		shouldError bool
 		errorType   string
 	}{
 		{
 			name:        "Valid deposit",
-			amount:      500.0,
+			amount:      usd("500"),
 			shouldError: false,
 		},
 		{
 			name:        "Zero deposit",
-			amount:      0.0,
+			amount:      usd("0"),
 			shouldError: false,
 		},
 		{
 			name:        "Negative deposit",
-			amount:      -100.0,
+			amount:      usd("-100"),
 			shouldError: true,
 			errorType:   "NegativeAmountError",
 		},
 		{
 			name:        "Exceeds limit deposit",
-			amount:      MaxTransactionAmount + 1.0,
+			amount:      maxTransaction("USD").Add(usd("0.01")),
 			shouldError: true,
 			errorType:   "ExceedsLimitError",
 		},
@@ -157,7 +159,7 @@ func TestDeposit(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			account, _ := NewBankAccount("TEST", "TestUser", 1000.0, 100.0)
+			account, _ := NewBankAccount("TEST", "TestUser", usd("1000"), usd("100"))
 			initialBalance := account.Balance
 
 			err := account.Deposit(tc.amount)
@@ -175,7 +177,7 @@ func TestDeposit(t *testing.T) {
 				// Balance should not change on error
 				if account.Balance != initialBalance {
 					t.Errorf(
-						"Expected balance to remain %.2f but got %.2f",
+						"Expected balance to remain %s but got %s",
 						initialBalance,
 						account.Balance,
 					)
@@ -187,9 +189,9 @@ func TestDeposit(t *testing.T) {
 				}
 
 				// Check if balance increased correctly
-				expectedBalance := initialBalance + tc.amount
+				expectedBalance := initialBalance.Add(tc.amount)
 				if account.Balance != expectedBalance {
-					t.Errorf("Expected balance %.2f but got %.2f", expectedBalance, account.Balance)
+					t.Errorf("Expected balance %s but got %s", expectedBalance, account.Balance)
 				}
 			}
 		})
@@ -199,35 +201,35 @@ func TestDeposit(t *testing.T) {
 func TestWithdraw(t *testing.T) {
 	testCases := []struct {
 		name        string
-		amount      float64
+		amount      Money
 		shouldError bool
 		errorType   string
 	}{
 		{
 			name:        "Valid withdrawal",
-			amount:      200.0,
+			amount:      usd("200"),
 			shouldError: false,
 		},
 		{
 			name:        "Zero withdrawal",
-			amount:      0.0,
+			amount:      usd("0"),
 			shouldError: false,
 		},
 		{
 			name:        "Negative withdrawal",
-			amount:      -100.0,
+			amount:      usd("-100"),
 			shouldError: true,
 			errorType:   "NegativeAmountError",
 		},
 		{
 			name:        "Exceeds limit withdrawal",
-			amount:      MaxTransactionAmount + 1.0,
+			amount:      maxTransaction("USD").Add(usd("0.01")),
 			shouldError: true,
 			errorType:   "ExceedsLimitError",
 		},
 		{
 			name:        "Insufficient funds withdrawal",
-			amount:      950.0, // Leaves 50, which is below min balance of 100
+			amount:      usd("950"), // Leaves 50, which is below min balance of 100
 			shouldError: true,
 			errorType:   "InsufficientFundsError",
 		},
@@ -235,7 +237,7 @@ func TestWithdraw(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			account, _ := NewBankAccount("TEST", "TestUser", 1000.0, 100.0)
+			account, _ := NewBankAccount("TEST", "TestUser", usd("1000"), usd("100"))
 			initialBalance := account.Balance
 
 			err := account.Withdraw(tc.amount)
@@ -253,7 +255,7 @@ func TestWithdraw(t *testing.T) {
 				// Balance should not change on error
 				if account.Balance != initialBalance {
 					t.Errorf(
-						"Expected balance to remain %.2f but got %.2f",
+						"Expected balance to remain %s but got %s",
 						initialBalance,
 						account.Balance,
 					)
@@ -265,9 +267,9 @@ func TestWithdraw(t *testing.T) {
 				}
 
 				// Check if balance decreased correctly
-				expectedBalance := initialBalance - tc.amount
+				expectedBalance := initialBalance.Sub(tc.amount)
 				if account.Balance != expectedBalance {
-					t.Errorf("Expected balance %.2f but got %.2f", expectedBalance, account.Balance)
+					t.Errorf("Expected balance %s but got %s", expectedBalance, account.Balance)
 				}
 			}
 		})
@@ -277,35 +279,35 @@ func TestWithdraw(t *testing.T) {
 func TestTransfer(t *testing.T) {
 	testCases := []struct {
 		name        string
-		amount      float64
+		amount      Money
 		shouldError bool
 		errorType   string
 	}{
 		{
 			name:        "Valid transfer",
-			amount:      300.0,
+			amount:      usd("300"),
 			shouldError: false,
 		},
 		{
 			name:        "Zero transfer",
-			amount:      0.0,
+			amount:      usd("0"),
 			shouldError: false,
 		},
 		{
 			name:        "Negative transfer",
-			amount:      -100.0,
+			amount:      usd("-100"),
 			shouldError: true,
 			errorType:   "NegativeAmountError",
 		},
 		{
 			name:        "Exceeds limit transfer",
-			amount:      MaxTransactionAmount + 1.0,
+			amount:      maxTransaction("USD").Add(usd("0.01")),
 			shouldError: true,
 			errorType:   "ExceedsLimitError",
 		},
 		{
 			name:        "Insufficient funds transfer",
-			amount:      950.0, // Leaves 50, which is below min balance of 100
+			amount:      usd("950"), // Leaves 50, which is below min balance of 100
 			shouldError: true,
 			errorType:   "InsufficientFundsError",
 		},
@@ -313,8 +315,8 @@ func TestTransfer(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			source, _ := NewBankAccount("SRC", "Source", 1000.0, 100.0)
-			target, _ := NewBankAccount("TGT", "Target", 500.0, 50.0)
+			source, _ := NewBankAccount("SRC", "Source", usd("1000"), usd("100"))
+			target, _ := NewBankAccount("TGT", "Target", usd("500"), usd("50"))
 
 			sourceInitialBalance := source.Balance
 			targetInitialBalance := target.Balance
@@ -334,7 +336,7 @@ func TestTransfer(t *testing.T) {
 				// Balances should not change on error
 				if source.Balance != sourceInitialBalance {
 					t.Errorf(
-						"Expected source balance to remain %.2f but got %.2f",
+						"Expected source balance to remain %s but got %s",
 						sourceInitialBalance,
 						source.Balance,
 					)
@@ -342,7 +344,7 @@ func TestTransfer(t *testing.T) {
 
 				if target.Balance != targetInitialBalance {
 					t.Errorf(
-						"Expected target balance to remain %.2f but got %.2f",
+						"Expected target balance to remain %s but got %s",
 						targetInitialBalance,
 						target.Balance,
 					)
@@ -354,12 +356,12 @@ func TestTransfer(t *testing.T) {
 				}
 
 				// Check if balances changed correctly
-				expectedSourceBalance := sourceInitialBalance - tc.amount
-				expectedTargetBalance := targetInitialBalance + tc.amount
+				expectedSourceBalance := sourceInitialBalance.Sub(tc.amount)
+				expectedTargetBalance := targetInitialBalance.Add(tc.amount)
 
 				if source.Balance != expectedSourceBalance {
 					t.Errorf(
-						"Expected source balance %.2f but got %.2f",
+						"Expected source balance %s but got %s",
 						expectedSourceBalance,
 						source.Balance,
 					)
@@ -367,7 +369,7 @@ func TestTransfer(t *testing.T) {
 
 				if target.Balance != expectedTargetBalance {
 					t.Errorf(
-						"Expected target balance %.2f but got %.2f",
+						"Expected target balance %s but got %s",
 						expectedTargetBalance,
 						target.Balance,
 					)
@@ -378,7 +380,7 @@ func TestTransfer(t *testing.T) {
 }
 
 func TestConcurrency(t *testing.T) {
-	account, _ := NewBankAccount("CONC", "Concurrency Test", 1000.0, 100.0)
+	account, _ := NewBankAccount("CONC", "Concurrency Test", usd("1000"), usd("100"))
 
 	const numOperations = 100
 	var wg sync.WaitGroup
@@ -388,7 +390,7 @@ func TestConcurrency(t *testing.T) {
 	for i := 0; i < numOperations; i++ {
 		go func() {
 			defer wg.Done()
-			_ = account.Deposit(10.0)
+			_ = account.Deposit(usd("10"))
 		}()
 	}
 
@@ -396,7 +398,7 @@ func TestConcurrency(t *testing.T) {
 	for i := 0; i < numOperations; i++ {
 		go func() {
 			defer wg.Done()
-			_ = account.Withdraw(5.0)
+			_ = account.Withdraw(usd("5"))
 		}()
 	}
 
@@ -407,13 +409,102 @@ func TestConcurrency(t *testing.T) {
 	// 100 deposits of 10: +1000
 	// 100 withdrawals of 5: -500
 	// Expected: 1500
-	expectedBalance := 1500.0
+	expectedBalance := usd("1500")
 
 	if account.Balance != expectedBalance {
 		t.Errorf(
-			"Expected balance after concurrent operations to be %.2f but got %.2f",
+			"Expected balance after concurrent operations to be %s but got %s",
 			expectedBalance,
 			account.Balance,
 		)
 	}
 }
+
+func TestRepeatedDepositsDoNotDrift(t *testing.T) {
+	account, _ := NewBankAccount("DRIFT", "Drift Test", usd("0"), Money{})
+	for range 1000 {
+		if err := account.Deposit(usd("0.1")); err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+	}
+	if account.Balance != usd("100") {
+		t.Errorf("Expected balance %s but got %s", usd("100"), account.Balance)
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	account, _ := NewBankAccount("USD1", "Dollars", usd("100"), Money{})
+	euros, _ := NewBankAccount("EUR1", "Euros", NewMoney(10000, "EUR"), Money{})
+
+	testCases := []struct {
+		name string
+		op   func() error
+	}{
+		{name: "Deposit", op: func() error { return account.Deposit(NewMoney(100, "EUR")) }},
+		{name: "Withdraw", op: func() error { return account.Withdraw(NewMoney(100, "EUR")) }},
+		{
+			name: "Transfer amount",
+			op:   func() error { return account.Transfer(NewMoney(100, "EUR"), euros) },
+		},
+		{name: "Transfer target", op: func() error { return account.Transfer(usd("1"), euros) }},
+		{
+			name: "Min balance",
+			op: func() error {
+				_, err := NewBankAccount("USD2", "Dollars", usd("100"), NewMoney(100, "EUR"))
+				return err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mismatch *CurrencyMismatchError
+			if err := tc.op(); !errors.As(err, &mismatch) {
+				t.Errorf("Expected CurrencyMismatchError but got %T", err)
+			}
+		})
+	}
+	if account.Balance != usd("100") || euros.Balance != NewMoney(10000, "EUR") {
+		t.Errorf(
+			"Expected balances to remain unchanged, got %s and %s",
+			account.Balance,
+			euros.Balance,
+		)
+	}
+
+	if _, err := NewBankAccount("NONE", "No currency", Money{}, Money{}); err == nil {
+		t.Error("Expected an error for an account without currency")
+	}
+}
+
+func TestBalanceOverflow(t *testing.T) {
+	maxUSD := NewMoney(math.MaxInt64, "USD")
+	account, _ := NewBankAccount("ACC", "Owner", usd("1"), Money{}, WithTransactionLimit(maxUSD))
+
+	if err := account.Deposit(maxUSD); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Expected ErrInvalidMoney but got: %v", err)
+	}
+	if account.Balance != usd("1") || len(account.Ledger()) != 1 {
+		t.Errorf("Expected the account to be unchanged, got %s", account.Balance)
+	}
+
+	// Withdrawals reach down to the floor of the overdraft, and no further.
+	overdrawn, _ := NewBankAccount(
+		"OVR",
+		"Owner",
+		usd("0"),
+		Money{},
+		WithOverdraft(maxUSD),
+		WithTransactionLimit(maxUSD),
+	)
+	if err := overdrawn.Withdraw(maxUSD); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	var insufficient *InsufficientFundsError
+	if err := overdrawn.Withdraw(NewMoney(1, "USD")); !errors.As(err, &insufficient) {
+		t.Errorf("Expected InsufficientFundsError but got: %v", err)
+	}
+	if overdrawn.Balance != maxUSD.Neg() {
+		t.Errorf("Expected balance %s, got %s", maxUSD.Neg(), overdrawn.Balance)
+	}
+}
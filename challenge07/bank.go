@@ -0,0 +1,767 @@
+package challenge07
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// dbTimeout bounds every database operation of a Bank.
+const dbTimeout = 1 * time.Second
+
+// Bank owns accounts by ID, and persists them with their ledgers to a SQLite
+// database. The accounts of a bank are only operated on through the bank, so
+// that their changes are persisted; it hands out snapshots of them.
+type Bank struct {
+	db       *sql.DB
+	now      func() time.Time
+	mu       sync.Mutex
+	accounts map[string]*BankAccount
+	keys     keyLocks
+}
+
+// OpenBank returns a bank backed by db, creating its tables if needed and
+// loading the accounts stored in them.
+func OpenBank(db *sql.DB) (*Bank, error) {
+	return openBank(db, time.Now)
+}
+
+// openBank is OpenBank with the clock of the bank.
+func openBank(db *sql.DB, now func() time.Time) (*Bank, error) {
+	b := &Bank{db: db, now: now, accounts: make(map[string]*BankAccount)}
+	if err := b.createTables(); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+	if err := b.load(); err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+	return b, nil
+}
+
+// OpenAccount opens an account with the given parameters, as NewBankAccount
+// does, and returns a snapshot of it. It returns an AccountError if the bank
+// already has an account with that ID.
+func (b *Bank) OpenAccount(
+	id, owner string,
+	initialBalance, minBalance Money,
+	opts ...AccountOption,
+) (AccountSnapshot, error) {
+	a, err := newBankAccount(id, owner, initialBalance, minBalance, b.now, opts...)
+	if err != nil {
+		return AccountSnapshot{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.accounts[id]; ok {
+		return AccountSnapshot{}, &AccountError{accountID: id, message: "account already exists"}
+	}
+	err = b.inTx(func(ctx context.Context, tx *sql.Tx) error {
+		if _, execErr := tx.ExecContext(
+			ctx,
+			`INSERT INTO accounts (id, owner, currency, balance, min_balance)
+			VALUES (?, ?, ?, ?, ?)`,
+			a.ID, a.Owner, a.currency, a.Balance.Units(), a.MinBalance.Units(),
+		); execErr != nil {
+			return execErr
+		}
+		if execErr := saveLimits(ctx, tx, a.ID, a.limits); execErr != nil {
+			return execErr
+		}
+		return insertEntries(ctx, tx, a.ID, a.ledger)
+	})
+	if err != nil {
+		return AccountSnapshot{}, err
+	}
+	b.accounts[id] = a
+	return a.snapshot(), nil
+}
+
+// Account returns a snapshot of the account with the given ID, or an
+// AccountError if there is none.
+func (b *Bank) Account(id string) (AccountSnapshot, error) {
+	a, err := b.account(id)
+	if err != nil {
+		return AccountSnapshot{}, err
+	}
+	return a.snapshot(), nil
+}
+
+// Accounts returns snapshots of the accounts of the bank, sorted by ID.
+func (b *Bank) Accounts() []AccountSnapshot {
+	b.mu.Lock()
+	accounts := make([]*BankAccount, 0, len(b.accounts))
+	for _, a := range b.accounts {
+		accounts = append(accounts, a)
+	}
+	b.mu.Unlock()
+
+	slices.SortFunc(accounts, func(x, y *BankAccount) int { return cmp.Compare(x.ID, y.ID) })
+	snapshots := make([]AccountSnapshot, len(accounts))
+	for i, a := range accounts {
+		snapshots[i] = a.snapshot()
+	}
+	return snapshots
+}
+
+// Deposit deposits amount to the account with the given ID, as
+// BankAccount.Deposit does.
+func (b *Bank) Deposit(id string, amount Money) error {
+	return b.deposit(id, amount, nil)
+}
+
+// Withdraw withdraws amount from the account with the given ID, as
+// BankAccount.Withdraw does.
+func (b *Bank) Withdraw(id string, amount Money) error {
+	return b.withdraw(id, amount, nil)
+}
+
+// Transfer moves amount between the accounts with the given IDs, as
+// BankAccount.Transfer does, in a single database transaction.
+func (b *Bank) Transfer(fromID, toID string, amount Money) error {
+	return b.transfer(fromID, toID, amount, nil, nil)
+}
+
+// TransferFX moves amount between the accounts with the given IDs, as
+// BankAccount.TransferFX does, in a single database transaction.
+func (b *Bank) TransferFX(fromID, toID string, amount Money, rates ExchangeRateProvider) error {
+	return b.transfer(fromID, toID, amount, rates, nil)
+}
+
+// SetLimits replaces the limits of the account with the given ID, as
+// BankAccount.SetLimits does.
+func (b *Bank) SetLimits(id string, limits Limits) error {
+	a, err := b.account(id)
+	if err != nil {
+		return err
+	}
+	limits, err = a.validateLimits(limits)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err = b.inTx(func(ctx context.Context, tx *sql.Tx) error {
+		return saveLimits(ctx, tx, id, limits)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to persist: %w", err)
+	}
+	a.limits = limits
+	return nil
+}
+
+// PlaceHold holds amount of the account with the given ID for the given
+// duration, as BankAccount.PlaceHold does.
+func (b *Bank) PlaceHold(id string, amount Money, duration time.Duration) (Hold, error) {
+	a, err := b.account(id)
+	if err != nil {
+		return Hold{}, err
+	}
+	if err = a.validateHold(amount, duration); err != nil {
+		return Hold{}, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var h Hold
+	err = b.apply([]*BankAccount{a}, nil, func() error {
+		var holdErr error
+		h, holdErr = a.placeHold(amount, duration)
+		return holdErr
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+	return h, nil
+}
+
+// CaptureHold captures amount of the hold with the given ID of the account
+// with the given ID, as BankAccount.CaptureHold does.
+func (b *Bank) CaptureHold(id string, holdID int64, amount Money) error {
+	a, err := b.account(id)
+	if err != nil {
+		return err
+	}
+	if err := a.validateCapture(amount); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return b.apply([]*BankAccount{a}, nil, func() error {
+		return a.captureHold(holdID, amount)
+	})
+}
+
+// ReleaseHold releases the hold with the given ID of the account with the
+// given ID, as BankAccount.ReleaseHold does.
+func (b *Bank) ReleaseHold(id string, holdID int64) error {
+	a, err := b.account(id)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return b.apply([]*BankAccount{a}, nil, func() error {
+		return a.releaseHold(holdID)
+	})
+}
+
+// account returns the account with the given ID, or an AccountError if there
+// is none.
+func (b *Bank) account(id string) (*BankAccount, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	a, ok := b.accounts[id]
+	if !ok {
+		return nil, &AccountError{accountID: id, message: "account not found"}
+	}
+	return a, nil
+}
+
+// deposit deposits amount to the account with the given ID, and persists
+// receipt with it if not nil.
+func (b *Bank) deposit(id string, amount Money, receipt *Receipt) error {
+	a, err := b.account(id)
+	if err != nil {
+		return err
+	}
+	if err := a.validateAmount(amount); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return b.apply([]*BankAccount{a}, receipt, func() error {
+		return a.transact(LedgerEntry{Type: EntryDeposit, Amount: amount, Time: a.clock()})
+	})
+}
+
+// withdraw withdraws amount from the account with the given ID, and persists
+// receipt with it if not nil.
+func (b *Bank) withdraw(id string, amount Money, receipt *Receipt) error {
+	a, err := b.account(id)
+	if err != nil {
+		return err
+	}
+	if err := a.validateAmount(amount); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return b.apply([]*BankAccount{a}, receipt, func() error {
+		return a.transact(LedgerEntry{Type: EntryWithdrawal, Amount: amount.Neg(), Time: a.clock()})
+	})
+}
+
+// transfer moves amount between the accounts with the given IDs, converting it
+// with rates if needed, and persists receipt with it if not nil.
+func (b *Bank) transfer(
+	fromID, toID string,
+	amount Money,
+	rates ExchangeRateProvider,
+	receipt *Receipt,
+) error {
+	if fromID == toID {
+		return &AccountError{accountID: fromID, message: "cannot transfer to the same account"}
+	}
+	from, err := b.account(fromID)
+	if err != nil {
+		return err
+	}
+	to, err := b.account(toID)
+	if err != nil {
+		return err
+	}
+	x, err := prepareTransfer(from, to, amount, rates)
+	if err != nil {
+		return err
+	}
+	unlock := lockPair(from, to)
+	defer unlock()
+	return b.apply([]*BankAccount{from, to}, receipt, func() error {
+		return transfer(from, to, x)
+	})
+}
+
+func (b *Bank) createTables() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := b.db.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS accounts (
+			id TEXT PRIMARY KEY,
+			owner TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			balance INTEGER NOT NULL,
+			min_balance INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS ledger_entries (
+			account_id TEXT NOT NULL REFERENCES accounts (id),
+			id INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			counterparty TEXT NOT NULL,
+			time INTEGER NOT NULL,
+			balance INTEGER NOT NULL,
+			PRIMARY KEY (account_id, id)
+		);
+		CREATE TABLE IF NOT EXISTS account_limits (
+			account_id TEXT PRIMARY KEY REFERENCES accounts (id),
+			transaction_limit INTEGER NOT NULL,
+			daily_limit INTEGER NOT NULL,
+			monthly_limit INTEGER NOT NULL,
+			overdraft INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS ledger_exchanges (
+			account_id TEXT NOT NULL,
+			entry_id INTEGER NOT NULL,
+			rate INTEGER NOT NULL,
+			source_amount INTEGER NOT NULL,
+			source_currency TEXT NOT NULL,
+			destination_amount INTEGER NOT NULL,
+			destination_currency TEXT NOT NULL,
+			PRIMARY KEY (account_id, entry_id),
+			FOREIGN KEY (account_id, entry_id) REFERENCES ledger_entries (account_id, id)
+		);
+		CREATE TABLE IF NOT EXISTS holds (
+			account_id TEXT NOT NULL REFERENCES accounts (id),
+			id INTEGER NOT NULL,
+			amount INTEGER NOT NULL,
+			placed_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL,
+			closed INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (account_id, id)
+		);
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			key TEXT PRIMARY KEY,
+			operation TEXT NOT NULL,
+			account_id TEXT NOT NULL,
+			counterparty TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			currency TEXT NOT NULL,
+			entry_id INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			balance INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+// load loads the accounts and their ledgers from the database.
+func (b *Bank) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := b.db.QueryContext(
+		ctx,
+		"SELECT id, owner, currency, balance, min_balance FROM accounts",
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Printf("rows close failed: %v", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var a BankAccount
+		var balance, minBalance int64
+		if err := rows.Scan(&a.ID, &a.Owner, &a.currency, &balance, &minBalance); err != nil {
+			return err
+		}
+		a.Balance = NewMoney(balance, a.currency)
+		a.MinBalance = NewMoney(minBalance, a.currency)
+		a.now = b.now
+		// Accounts stored before limits were persisted have the default ones,
+		// which are valid.
+		a.limits, _ = a.validateLimits(Limits{})
+		b.accounts[a.ID] = &a
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := b.loadLimits(ctx); err != nil {
+		return err
+	}
+	if err := b.loadHolds(ctx); err != nil {
+		return err
+	}
+	return b.loadLedgers(ctx)
+}
+
+func (b *Bank) loadLimits(ctx context.Context) error {
+	rows, err := b.db.QueryContext(
+		ctx,
+		`SELECT account_id, transaction_limit, daily_limit, monthly_limit, overdraft
+		FROM account_limits`,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Printf("rows close failed: %v", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var accountID string
+		var transaction, daily, monthly, overdraft int64
+		if err := rows.Scan(&accountID, &transaction, &daily, &monthly, &overdraft); err != nil {
+			return err
+		}
+		a, ok := b.accounts[accountID]
+		if !ok {
+			return fmt.Errorf("limits of unknown account %q", accountID)
+		}
+		a.limits = Limits{
+			Transaction: NewMoney(transaction, a.currency),
+			Daily:       NewMoney(daily, a.currency),
+			Monthly:     NewMoney(monthly, a.currency),
+			Overdraft:   NewMoney(overdraft, a.currency),
+		}
+	}
+	return rows.Err()
+}
+
+// loadHolds loads the open holds of the accounts. Closed holds, which were
+// captured, released or expired, only number the next holds.
+func (b *Bank) loadHolds(ctx context.Context) error {
+	rows, err := b.db.QueryContext(
+		ctx,
+		"SELECT account_id, id, amount, placed_at, expires_at, closed FROM holds",
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Printf("rows close failed: %v", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var accountID string
+		var h Hold
+		var amount, placedAt, expiresAt int64
+		var closed bool
+		if err := rows.Scan(
+			&accountID,
+			&h.ID,
+			&amount,
+			&placedAt,
+			&expiresAt,
+			&closed,
+		); err != nil {
+			return err
+		}
+		a, ok := b.accounts[accountID]
+		if !ok {
+			return fmt.Errorf("hold %d of unknown account %q", h.ID, accountID)
+		}
+		a.lastHold = max(a.lastHold, h.ID)
+		if closed {
+			continue
+		}
+		h.Amount = NewMoney(amount, a.currency)
+		h.PlacedAt = time.Unix(0, placedAt).UTC()
+		h.ExpiresAt = time.Unix(0, expiresAt).UTC()
+		if a.holds == nil {
+			a.holds = make(map[int64]Hold)
+		}
+		a.holds[h.ID] = h
+	}
+	return rows.Err()
+}
+
+func (b *Bank) loadLedgers(ctx context.Context) error {
+	rows, err := b.db.QueryContext(
+		ctx,
+		`SELECT account_id, id, type, amount, counterparty, time, balance
+		FROM ledger_entries ORDER BY account_id, id`,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Printf("rows close failed: %v", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var accountID string
+		var e LedgerEntry
+		var amount, at, balance int64
+		if err := rows.Scan(
+			&accountID,
+			&e.ID,
+			&e.Type,
+			&amount,
+			&e.Counterparty,
+			&at,
+			&balance,
+		); err != nil {
+			return err
+		}
+		a, ok := b.accounts[accountID]
+		if !ok {
+			return fmt.Errorf("ledger entry %d of unknown account %q", e.ID, accountID)
+		}
+		e.Amount = NewMoney(amount, a.currency)
+		e.Time = time.Unix(0, at).UTC()
+		e.Balance = NewMoney(balance, a.currency)
+		a.ledger = append(a.ledger, e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return b.loadExchanges(ctx)
+}
+
+// loadExchanges loads the exchanges of the loaded ledger entries.
+func (b *Bank) loadExchanges(ctx context.Context) error {
+	rows, err := b.db.QueryContext(
+		ctx,
+		`SELECT account_id, entry_id, rate, source_amount, source_currency,
+		destination_amount, destination_currency
+		FROM ledger_exchanges`,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Printf("rows close failed: %v", cerr)
+		}
+	}()
+
+	for rows.Next() {
+		var accountID string
+		var entryID, source, destination int64
+		var sourceCurrency, destinationCurrency Currency
+		var x Exchange
+		if err := rows.Scan(
+			&accountID,
+			&entryID,
+			&x.Rate.units,
+			&source,
+			&sourceCurrency,
+			&destination,
+			&destinationCurrency,
+		); err != nil {
+			return err
+		}
+		a, ok := b.accounts[accountID]
+		if !ok || entryID < 1 || entryID > int64(len(a.ledger)) {
+			return fmt.Errorf("exchange of unknown ledger entry %d of %q", entryID, accountID)
+		}
+		x.Source = NewMoney(source, sourceCurrency)
+		x.Destination = NewMoney(destination, destinationCurrency)
+		a.ledger[entryID-1].Exchange = x
+	}
+	return rows.Err()
+}
+
+// apply runs op on locked accounts, and persists the changes it made to them in
+// a single database transaction. It undoes the changes if op or the
+// transaction fails. If receipt is not nil, apply completes it with the last
+// entry of the first account, and persists it in the same transaction.
+func (b *Bank) apply(accounts []*BankAccount, receipt *Receipt, op func() error) error {
+	saved := make([]accountState, len(accounts))
+	for i, a := range accounts {
+		saved[i] = a.state()
+	}
+	undo := func() {
+		for i, a := range accounts {
+			a.restore(saved[i])
+		}
+	}
+	if err := op(); err != nil {
+		undo()
+		return err
+	}
+	if receipt != nil {
+		receipt.complete(accounts[0].ledger[len(accounts[0].ledger)-1])
+	}
+
+	err := b.inTx(func(ctx context.Context, tx *sql.Tx) error {
+		if receipt != nil {
+			if err := insertReceipt(ctx, tx, receipt); err != nil {
+				return err
+			}
+		}
+		for i, a := range accounts {
+			if err := saveChanges(ctx, tx, a, saved[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		undo()
+		return fmt.Errorf("failed to persist: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a database transaction, which it commits if fn succeeds.
+func (b *Bank) inTx(fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(ctx, tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("%v", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveLimits inserts or replaces the limits of an account.
+func saveLimits(ctx context.Context, tx *sql.Tx, accountID string, limits Limits) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO account_limits
+		(account_id, transaction_limit, daily_limit, monthly_limit, overdraft)
+		VALUES (?, ?, ?, ?, ?)`,
+		accountID,
+		limits.Transaction.Units(),
+		limits.Daily.Units(),
+		limits.Monthly.Units(),
+		limits.Overdraft.Units(),
+	)
+	return err
+}
+
+// saveChanges persists the changes to an account since it was in state saved.
+func saveChanges(ctx context.Context, tx *sql.Tx, a *BankAccount, saved accountState) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE accounts SET balance = ? WHERE id = ?",
+		a.Balance.Units(), a.ID,
+	); err != nil {
+		return err
+	}
+	if err := insertEntries(ctx, tx, a.ID, a.ledger[saved.entries:]); err != nil {
+		return err
+	}
+	return saveHolds(ctx, tx, a.ID, saved.holds, a.holds)
+}
+
+// saveHolds inserts the holds of an account that are in holds but not in
+// before, and closes those that are in before but not in holds.
+func saveHolds(
+	ctx context.Context,
+	tx *sql.Tx,
+	accountID string,
+	before, holds map[int64]Hold,
+) error {
+	for id := range before {
+		if _, ok := holds[id]; ok {
+			continue
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			"UPDATE holds SET closed = 1 WHERE account_id = ? AND id = ?",
+			accountID, id,
+		); err != nil {
+			return err
+		}
+	}
+	for id, h := range holds {
+		if _, ok := before[id]; ok {
+			continue
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO holds (account_id, id, amount, placed_at, expires_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			accountID,
+			h.ID,
+			h.Amount.Units(),
+			h.PlacedAt.UnixNano(),
+			h.ExpiresAt.UnixNano(),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertEntries(ctx context.Context, tx *sql.Tx, accountID string, entries []LedgerEntry) error {
+	for _, e := range entries {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO ledger_entries
+			(account_id, id, type, amount, counterparty, time, balance)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			accountID,
+			e.ID,
+			e.Type,
+			e.Amount.Units(),
+			e.Counterparty,
+			e.Time.UnixNano(),
+			e.Balance.Units(),
+		); err != nil {
+			return err
+		}
+		if e.Exchange.Rate.IsZero() {
+			continue
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO ledger_exchanges
+			(account_id, entry_id, rate, source_amount, source_currency,
+			destination_amount, destination_currency)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			accountID,
+			e.ID,
+			e.Exchange.Rate.units,
+			e.Exchange.Source.Units(),
+			e.Exchange.Source.Currency(),
+			e.Exchange.Destination.Units(),
+			e.Exchange.Destination.Currency(),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountState is what an operation may change about an account.
+type accountState struct {
+	balance  Money
+	entries  int
+	holds    map[int64]Hold
+	lastHold int64
+}
+
+func (a *BankAccount) state() accountState {
+	return accountState{
+		balance:  a.Balance,
+		entries:  len(a.ledger),
+		holds:    maps.Clone(a.holds),
+		lastHold: a.lastHold,
+	}
+}
+
+func (a *BankAccount) restore(s accountState) {
+	a.Balance = s.balance
+	a.ledger = a.ledger[:s.entries]
+	a.holds = s.holds
+	a.lastHold = s.lastHold
+}
@@ -0,0 +1,239 @@
+package challenge07
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// Registers the "sqlite3" database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens a SQLite database in a temporary file, which outlives the
+// connection so that tests can reopen it.
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("could not open test database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func openTestBank(t *testing.T, path string) *Bank {
+	t.Helper()
+	bank, err := openBank(openTestDB(t, path), newTestClock().now)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	return bank
+}
+
+func TestBankPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+
+	if _, err := bank.OpenAccount("ACC001", "Alice", usd("1000"), usd("100")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if _, err := bank.OpenAccount("ACC002", "Bob", usd("500"), Money{}); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := bank.Deposit("ACC001", usd("0.10")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := bank.Withdraw("ACC002", usd("50")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := bank.Transfer("ACC001", "ACC002", usd("300")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	var insufficient *InsufficientFundsError
+	if err := bank.Transfer("ACC001", "ACC002", usd("700")); !errors.As(err, &insufficient) {
+		t.Fatalf("Expected InsufficientFundsError but got %T", err)
+	}
+
+	reopened := openTestBank(t, path)
+	accounts := reopened.Accounts()
+	if len(accounts) != 2 {
+		t.Fatalf("Expected 2 accounts, got %d", len(accounts))
+	}
+	for _, want := range bank.Accounts() {
+		got, err := reopened.Account(want.ID)
+		if err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+		if got.Owner != want.Owner || got.Balance != want.Balance ||
+			got.MinBalance != want.MinBalance || got.Currency() != want.Currency() {
+			t.Errorf("Expected account %+v, got %+v", want, got)
+		}
+		wantLedger, gotLedger := want.Ledger(), got.Ledger()
+		if len(gotLedger) != len(wantLedger) {
+			t.Fatalf("Expected %d entries, got %d", len(wantLedger), len(gotLedger))
+		}
+		for i := range wantLedger {
+			if gotLedger[i] != wantLedger[i] {
+				t.Errorf("Entry %d: expected %+v, got %+v", i, wantLedger[i], gotLedger[i])
+			}
+		}
+	}
+
+	alice, _ := reopened.Account("ACC001")
+	if alice.Balance != usd("700.10") {
+		t.Errorf("Expected balance %s, got %s", usd("700.10"), alice.Balance)
+	}
+	// The reloaded accounts keep working.
+	if err := reopened.Deposit("ACC001", usd("1")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	// Snapshots do not change with the account.
+	if n := len(alice.Ledger()); n != 3 || alice.Balance != usd("700.10") {
+		t.Errorf("Expected the snapshot to be unchanged, got %s with %d entries", alice.Balance, n)
+	}
+	alice, _ = reopened.Account("ACC001")
+	if n := len(alice.Ledger()); n != 4 || alice.Balance != usd("701.10") {
+		t.Errorf(
+			"Expected balance %s with 4 entries, got %s with %d",
+			usd("701.10"),
+			alice.Balance,
+			n,
+		)
+	}
+	all := alice.Statement(time.Time{}, time.Now())
+	if len(all.Entries) != 4 || all.ClosingBalance != alice.Balance {
+		t.Errorf("Expected a statement of all 4 entries, got %+v", all)
+	}
+}
+
+func TestBankErrors(t *testing.T) {
+	bank := openTestBank(t, filepath.Join(t.TempDir(), "bank.db"))
+	if _, err := bank.OpenAccount("ACC001", "Alice", usd("100"), Money{}); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		op        func() error
+		errorType string
+	}{
+		{
+			name: "Duplicate account",
+			op: func() error {
+				_, err := bank.OpenAccount("ACC001", "Eve", usd("1"), Money{})
+				return err
+			},
+			errorType: "AccountError",
+		},
+		{
+			name: "Invalid account",
+			op: func() error {
+				_, err := bank.OpenAccount("", "Eve", usd("1"), Money{})
+				return err
+			},
+			errorType: "AccountError",
+		},
+		{
+			name:      "Unknown account",
+			op:        func() error { return bank.Deposit("ACC404", usd("1")) },
+			errorType: "AccountError",
+		},
+		{
+			name:      "Unknown target",
+			op:        func() error { return bank.Transfer("ACC001", "ACC404", usd("1")) },
+			errorType: "AccountError",
+		},
+		{
+			name:      "Same account",
+			op:        func() error { return bank.Transfer("ACC001", "ACC001", usd("1")) },
+			errorType: "AccountError",
+		},
+		{
+			name:      "Negative amount",
+			op:        func() error { return bank.Withdraw("ACC001", usd("-1")) },
+			errorType: "NegativeAmountError",
+		},
+		{
+			name:      "Insufficient funds",
+			op:        func() error { return bank.Withdraw("ACC001", usd("100.01")) },
+			errorType: "InsufficientFundsError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.op()
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !strings.Contains(fmt.Sprintf("%T", err), tc.errorType) {
+				t.Errorf("Expected error of type %s but got %T", tc.errorType, err)
+			}
+		})
+	}
+}
+
+func TestBankUndoesChangesWhenPersistingFails(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "bank.db"))
+	bank, err := openBank(db, newTestClock().now)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("100"), Money{})
+	_, _ = bank.OpenAccount("ACC002", "Bob", usd("100"), Money{})
+	if _, err := db.Exec("DROP TABLE ledger_entries"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bank.Transfer("ACC001", "ACC002", usd("10")); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	for _, a := range bank.Accounts() {
+		if a.Balance != usd("100") || len(a.Ledger()) != 1 {
+			t.Errorf("Expected %s to be unchanged, got %s with %d entries",
+				a.ID, a.Balance, len(a.Ledger()))
+		}
+	}
+}
+
+func TestBankConcurrentTransfers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+	ids := []string{"A", "B", "C"}
+	for _, id := range ids {
+		if _, err := bank.OpenAccount(id, "Owner "+id, usd("100"), Money{}); err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Go(func() {
+			from, to := ids[i%3], ids[(i+1)%3]
+			_ = bank.Transfer(from, to, usd("7"))
+		})
+	}
+	wg.Wait()
+
+	total := usd("0")
+	for _, a := range openTestBank(t, path).Accounts() {
+		total = total.Add(a.Balance)
+		live, _ := bank.Account(a.ID)
+		if a.Balance != live.Balance {
+			t.Errorf(
+				"Expected stored balance of %s to be %s, got %s",
+				a.ID,
+				live.Balance,
+				a.Balance,
+			)
+		}
+	}
+	if total != usd("300") {
+		t.Errorf("Expected a total of %s, got %s", usd("300"), total)
+	}
+}
@@ -0,0 +1,138 @@
+package challenge07
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// rateDecimals is the number of decimals that a Rate holds.
+const rateDecimals = 8
+
+var (
+	// ErrInvalidRate is returned when parsing an invalid exchange rate.
+	ErrInvalidRate = errors.New("invalid exchange rate")
+	// ErrNoExchangeRate is returned by exchange rate providers that have no
+	// rate for a pair of currencies.
+	ErrNoExchangeRate = errors.New("no exchange rate")
+)
+
+// Rate is a positive exchange rate, held exactly as a decimal number with up to
+// 8 decimals. The zero Rate is no rate.
+type Rate struct {
+	units int64
+}
+
+// ParseRate parses a positive decimal exchange rate, such as "0.9215".
+func ParseRate(s string) (Rate, error) {
+	units, err := parseDecimal(s, rateDecimals)
+	if err != nil || units <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	return Rate{units: units}, nil
+}
+
+// IsZero reports whether r is the zero Rate.
+func (r Rate) IsZero() bool {
+	return r.units == 0
+}
+
+// Convert returns m converted to currency at rate r, rounded half away from
+// zero to the minor unit of currency.
+func (r Rate) Convert(m Money, currency Currency) (Money, error) {
+	if r.units <= 0 {
+		return Money{}, fmt.Errorf("%w: %s", ErrInvalidRate, r)
+	}
+	num := big.NewInt(m.units)
+	num.Mul(num, big.NewInt(r.units))
+	num.Mul(num, big.NewInt(currency.scale()))
+	den := big.NewInt(m.currency.scale() * pow10(rateDecimals))
+
+	units, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(den) >= 0 {
+		units.Add(units, big.NewInt(int64(num.Sign())))
+	}
+	if !units.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s at %s out of range", ErrInvalidMoney, m, r)
+	}
+	return Money{units: units.Int64(), currency: currency}, nil
+}
+
+// String formats the rate without trailing zeros, such as "0.9215".
+func (r Rate) String() string {
+	scale := pow10(rateDecimals)
+	s := fmt.Sprintf("%d.%0*d", r.units/scale, rateDecimals, r.units%scale)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// ExchangeRateProvider provides the exchange rates of transfers between
+// accounts in different currencies.
+type ExchangeRateProvider interface {
+	// Rate returns the rate at which an amount of from converts to to, or an
+	// error wrapping ErrNoExchangeRate if there is none.
+	Rate(from, to Currency) (Rate, error)
+}
+
+// CurrencyPair is the pair of currencies of an exchange rate.
+type CurrencyPair struct {
+	From, To Currency
+}
+
+// StaticRates is an ExchangeRateProvider with fixed rates, which does not
+// derive the rates of inverse or chained pairs.
+type StaticRates map[CurrencyPair]Rate
+
+// Rate returns the rate of the pair from, to.
+func (s StaticRates) Rate(from, to Currency) (Rate, error) {
+	rate, ok := s[CurrencyPair{From: from, To: to}]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, from, to)
+	}
+	return rate, nil
+}
+
+// Exchange records the conversion of a transfer between currencies.
+type Exchange struct {
+	Rate Rate
+	// Source is the amount that left the source account, in its currency.
+	Source Money
+	// Destination is the amount that reached the destination account, in its
+	// currency.
+	Destination Money
+}
+
+// ExchangeRateError occurs when a transfer between currencies cannot be converted.
+type ExchangeRateError struct {
+	accountID string
+	from      Currency
+	to        Currency
+	err       error
+}
+
+func (e *ExchangeRateError) Error() string {
+	return fmt.Sprintf(
+		"account ID: %s, cannot exchange %s to %s: %v",
+		e.accountID,
+		e.from,
+		e.to,
+		e.err,
+	)
+}
+
+func (e *ExchangeRateError) Unwrap() error {
+	return e.err
+}
+
+// exchange converts amount to currency at the rate that rates gives.
+func exchange(amount Money, currency Currency, rates ExchangeRateProvider) (Exchange, error) {
+	rate, err := rates.Rate(amount.Currency(), currency)
+	if err != nil {
+		return Exchange{}, err
+	}
+	destination, err := rate.Convert(amount, currency)
+	if err != nil {
+		return Exchange{}, err
+	}
+	return Exchange{Rate: rate, Source: amount, Destination: destination}, nil
+}
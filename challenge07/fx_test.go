@@ -0,0 +1,261 @@
+package challenge07
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rate parses an exchange rate, panicking if it is invalid.
+func rate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// money parses an amount of currency, panicking if it is invalid.
+func money(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func TestParseRate(t *testing.T) {
+	testCases := []struct {
+		input   string
+		wantStr string
+		wantErr bool
+	}{
+		{input: "0.9215", wantStr: "0.9215"},
+		{input: "151.2", wantStr: "151.2"},
+		{input: "1", wantStr: "1"},
+		{input: "0.00000001", wantStr: "0.00000001"},
+		{input: "0.000000001", wantErr: true},
+		{input: "0", wantErr: true},
+		{input: "-1.5", wantErr: true},
+		{input: "1,5", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			r, err := ParseRate(tc.input)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidRate) {
+					t.Errorf("Expected ErrInvalidRate but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if r.String() != tc.wantStr {
+				t.Errorf("Expected %q, got %q", tc.wantStr, r.String())
+			}
+		})
+	}
+}
+
+func TestRateConvert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   Money
+		rate     string
+		currency Currency
+		want     Money
+	}{
+		{
+			name:     "USD to EUR",
+			amount:   usd("100"),
+			rate:     "0.9215",
+			currency: "EUR",
+			want:     money("92.15", "EUR"),
+		},
+		{
+			name:     "Rounds half up",
+			amount:   usd("0.01"),
+			rate:     "150.5",
+			currency: "JPY",
+			want:     money("2", "JPY"),
+		},
+		{
+			name:     "Rounds down",
+			amount:   usd("0.01"),
+			rate:     "149.99",
+			currency: "JPY",
+			want:     money("1", "JPY"),
+		},
+		{
+			name:     "Rounds negative amounts away from zero",
+			amount:   usd("-0.01"),
+			rate:     "150.5",
+			currency: "JPY",
+			want:     money("-2", "JPY"),
+		},
+		{
+			name:     "JPY to USD",
+			amount:   money("1500", "JPY"),
+			rate:     "0.0066",
+			currency: "USD",
+			want:     usd("9.90"),
+		},
+		{
+			name:     "USD to KWD",
+			amount:   usd("10"),
+			rate:     "0.3075",
+			currency: "KWD",
+			want:     money("3.075", "KWD"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := rate(tc.rate).Convert(tc.amount, tc.currency)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := (Rate{}).Convert(usd("1"), "EUR"); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Expected ErrInvalidRate but got: %v", err)
+	}
+	if _, err := rate("1000").Convert(NewMoney(1<<62, "USD"), "EUR"); err == nil {
+		t.Error("Expected error but got none")
+	}
+}
+
+func TestTransferFX(t *testing.T) {
+	rates := StaticRates{{From: "USD", To: "EUR"}: rate("0.9215")}
+	clock := newTestClock()
+	source, _ := newBankAccount("SRC", "Source", usd("100"), Money{}, clock.now)
+	target, _ := newBankAccount("TGT", "Target", money("10", "EUR"), Money{}, clock.now)
+
+	if err := source.TransferFX(usd("50"), target, rates); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if source.Balance != usd("50") || target.Balance != money("56.08", "EUR") {
+		t.Errorf("Expected balances 50.00 USD and 56.08 EUR, got %s and %s",
+			source.Balance, target.Balance)
+	}
+	want := Exchange{Rate: rate("0.9215"), Source: usd("50"), Destination: money("46.08", "EUR")}
+	out, in := source.Ledger()[1], target.Ledger()[1]
+	if out.Exchange != want || out.Amount != usd("-50") {
+		t.Errorf("Expected source entry with exchange %+v, got %+v", want, out)
+	}
+	if in.Exchange != want || in.Amount != money("46.08", "EUR") {
+		t.Errorf("Expected target entry with exchange %+v, got %+v", want, in)
+	}
+
+	// Transfers in a single currency need no rate, and record no exchange.
+	other, _ := newBankAccount("OTH", "Other", usd("0"), Money{}, clock.now)
+	if err := source.TransferFX(usd("10"), other, StaticRates{}); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if x := other.Ledger()[1].Exchange; x != (Exchange{}) {
+		t.Errorf("Expected no exchange, got %+v", x)
+	}
+}
+
+func TestTransferFXErrors(t *testing.T) {
+	rates := StaticRates{{From: "USD", To: "EUR"}: rate("0.9215")}
+
+	testCases := []struct {
+		name      string
+		amount    Money
+		currency  Currency
+		rates     ExchangeRateProvider
+		errorType string
+		wantErr   error
+	}{
+		{
+			name:      "No rate",
+			amount:    usd("10"),
+			currency:  "GBP",
+			rates:     rates,
+			errorType: "ExchangeRateError",
+			wantErr:   ErrNoExchangeRate,
+		},
+		{
+			name:      "No provider",
+			amount:    usd("10"),
+			currency:  "EUR",
+			errorType: "CurrencyMismatchError",
+		},
+		{
+			name:      "Insufficient funds",
+			amount:    usd("500"),
+			currency:  "EUR",
+			rates:     rates,
+			errorType: "InsufficientFundsError",
+		},
+		{
+			name:      "Amount in the target currency",
+			amount:    money("10", "EUR"),
+			currency:  "EUR",
+			rates:     rates,
+			errorType: "CurrencyMismatchError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source, _ := NewBankAccount("SRC", "Source", usd("100"), Money{})
+			target, _ := NewBankAccount("TGT", "Target", money("0", tc.currency), Money{})
+			err := source.TransferFX(tc.amount, target, tc.rates)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !strings.Contains(fmt.Sprintf("%T", err), tc.errorType) {
+				t.Errorf("Expected error of type %s but got %T", tc.errorType, err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected error wrapping %v, got: %v", tc.wantErr, err)
+			}
+			if source.Balance != usd("100") || len(source.Ledger()) != 1 ||
+				len(target.Ledger()) != 1 {
+				t.Error("Expected the accounts to be unchanged")
+			}
+		})
+	}
+}
+
+func TestBankTransferFX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("100"), Money{})
+	_, _ = bank.OpenAccount("ACC002", "Bob", money("0", "JPY"), Money{})
+	rates := StaticRates{{From: "USD", To: "JPY"}: rate("151.23")}
+
+	if err := bank.TransferFX("ACC001", "ACC002", usd("10"), rates); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	var rateErr *ExchangeRateError
+	err := bank.TransferFX("ACC002", "ACC001", money("1", "JPY"), rates)
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected ExchangeRateError but got %T", err)
+	}
+
+	reopened := openTestBank(t, path)
+	for _, id := range []string{"ACC001", "ACC002"} {
+		want, _ := bank.Account(id)
+		got, _ := reopened.Account(id)
+		wantLedger, gotLedger := want.Ledger(), got.Ledger()
+		if got.Balance != want.Balance || len(gotLedger) != 2 || gotLedger[1] != wantLedger[1] {
+			t.Errorf("Expected %s to be reloaded as %+v, got %+v", id, wantLedger, gotLedger)
+		}
+	}
+	bob, _ := reopened.Account("ACC002")
+	if x := bob.Ledger()[1].Exchange; x.Destination != money("1512", "JPY") {
+		t.Errorf("Expected 1512 JPY to reach Bob, got %+v", x)
+	}
+}
@@ -0,0 +1,195 @@
+package challenge07
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Hold reserves an amount of an account for a later debit, such as a card
+// authorization, until it is captured, released or expires. Holds reduce the
+// available balance of the account, but not its Balance.
+type Hold struct {
+	// ID numbers the holds of an account from 1.
+	ID        int64
+	Amount    Money
+	PlacedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// active reports whether the hold has not expired at at.
+func (h Hold) active(at time.Time) bool {
+	return at.Before(h.ExpiresAt)
+}
+
+// AvailableBalance returns the balance of the account less the amounts on hold.
+func (a *BankAccount) AvailableBalance() Money {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.Balance.Sub(a.held(a.clock()))
+}
+
+// Holds returns the active holds of the account, oldest first.
+func (a *BankAccount) Holds() []Hold {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.activeHolds(a.clock())
+}
+
+// activeHolds returns the holds of the locked account active at at, oldest
+// first.
+func (a *BankAccount) activeHolds(at time.Time) []Hold {
+	holds := make([]Hold, 0, len(a.holds))
+	for _, h := range a.holds {
+		if h.active(at) {
+			holds = append(holds, h)
+		}
+	}
+	slices.SortFunc(holds, func(x, y Hold) int { return cmp.Compare(x.ID, y.ID) })
+	return holds
+}
+
+// PlaceHold holds amount of the account for the given duration, after which
+// the hold expires. It returns an error if the amount is invalid, exceeds a
+// limit of the account, or would bring the available balance below the
+// minimum required balance and the overdraft of the account.
+func (a *BankAccount) PlaceHold(amount Money, duration time.Duration) (Hold, error) {
+	if err := a.validateHold(amount, duration); err != nil {
+		return Hold{}, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.placeHold(amount, duration)
+}
+
+// CaptureHold debits amount, up to the amount of the hold with the given ID,
+// from the account, and releases the hold with any amount left. It returns an
+// AccountError if the amount is zero, or if the hold does not exist or has
+// expired: use ReleaseHold to release a hold without debiting the account.
+func (a *BankAccount) CaptureHold(id int64, amount Money) error {
+	if err := a.validateCapture(amount); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.captureHold(id, amount)
+}
+
+// ReleaseHold releases the hold with the given ID without debiting the
+// account. It returns an AccountError if the hold does not exist or has
+// expired.
+func (a *BankAccount) ReleaseHold(id int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.releaseHold(id)
+}
+
+// validateHold validates the amount and duration of a hold.
+func (a *BankAccount) validateHold(amount Money, duration time.Duration) error {
+	if err := a.validateAmount(amount); err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return &AccountError{accountID: a.ID, message: "hold duration must be positive"}
+	}
+	return nil
+}
+
+// validateCapture validates the amount of a capture, which must not be zero.
+func (a *BankAccount) validateCapture(amount Money) error {
+	if err := a.validateAmount(amount); err != nil {
+		return err
+	}
+	if amount.IsZero() {
+		return &AccountError{accountID: a.ID, message: "capture amount must be positive"}
+	}
+	return nil
+}
+
+// placeHold places a validated hold on the locked account.
+func (a *BankAccount) placeHold(amount Money, duration time.Duration) (Hold, error) {
+	at := a.clock()
+	// Holds are limited as the withdrawals that their captures are.
+	if err := a.checkLimits(LedgerEntry{
+		Type:   EntryWithdrawal,
+		Amount: amount.Neg(),
+		Time:   at,
+	}); err != nil {
+		return Hold{}, err
+	}
+	if err := a.checkFunds(amount.Neg(), at); err != nil {
+		return Hold{}, err
+	}
+
+	a.pruneHolds(at)
+	if a.holds == nil {
+		a.holds = make(map[int64]Hold)
+	}
+	a.lastHold++
+	h := Hold{ID: a.lastHold, Amount: amount, PlacedAt: at, ExpiresAt: at.Add(duration)}
+	a.holds[h.ID] = h
+	return h, nil
+}
+
+// captureHold captures a validated amount of a hold of the locked account.
+func (a *BankAccount) captureHold(id int64, amount Money) error {
+	at := a.clock()
+	h, err := a.hold(id, at)
+	if err != nil {
+		return err
+	}
+	if amount.Cmp(h.Amount) > 0 {
+		return a.exceeds(HoldLimit, amount, h.Amount)
+	}
+	delete(a.holds, id)
+	capture := LedgerEntry{Type: EntryCapture, Amount: amount.Neg(), Time: at}
+	if err := a.transact(capture); err != nil {
+		a.holds[id] = h
+		return err
+	}
+	return nil
+}
+
+// releaseHold releases a hold of the locked account.
+func (a *BankAccount) releaseHold(id int64) error {
+	if _, err := a.hold(id, a.clock()); err != nil {
+		return err
+	}
+	delete(a.holds, id)
+	return nil
+}
+
+// hold returns the hold of the locked account with the given ID if it is
+// active at at.
+func (a *BankAccount) hold(id int64, at time.Time) (Hold, error) {
+	h, ok := a.holds[id]
+	if !ok {
+		return Hold{}, &AccountError{accountID: a.ID, message: fmt.Sprintf("hold %d not found", id)}
+	}
+	if !h.active(at) {
+		delete(a.holds, id)
+		return Hold{}, &AccountError{accountID: a.ID, message: fmt.Sprintf("hold %d expired", id)}
+	}
+	return h, nil
+}
+
+// held returns the total of the holds of the locked account active at at.
+func (a *BankAccount) held(at time.Time) Money {
+	total := NewMoney(0, a.currency)
+	for _, h := range a.holds {
+		if h.active(at) {
+			total = total.Add(h.Amount)
+		}
+	}
+	return total
+}
+
+// pruneHolds forgets the holds of the locked account that expired at at.
+func (a *BankAccount) pruneHolds(at time.Time) {
+	for id, h := range a.holds {
+		if !h.active(at) {
+			delete(a.holds, id)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package challenge07
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHolds(t *testing.T) {
+	clock := newTestClock()
+	account, _ := newBankAccount("ACC", "Owner", usd("500"), usd("100"), clock.now)
+
+	first, err := account.PlaceHold(usd("150"), time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	second, err := account.PlaceHold(usd("200"), time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if first.ID != 1 || second.ID != 2 || second.ExpiresAt != second.PlacedAt.Add(time.Hour) {
+		t.Errorf("Unexpected holds %+v and %+v", first, second)
+	}
+	if account.Balance != usd("500") || account.AvailableBalance() != usd("150") {
+		t.Errorf("Expected balance 500.00 USD with 150.00 USD available, got %s with %s",
+			account.Balance, account.AvailableBalance())
+	}
+
+	// Holds reserve their amounts from withdrawals and other holds.
+	var insufficient *InsufficientFundsError
+	if err := account.Withdraw(usd("50.01")); !errors.As(err, &insufficient) {
+		t.Fatalf("Expected InsufficientFundsError but got %T", err)
+	}
+	if !strings.Contains(insufficient.Error(), "350.00 USD on hold") {
+		t.Errorf("Expected the error to mention the held amount, got %q", insufficient.Error())
+	}
+	if _, err := account.PlaceHold(usd("50.01"), time.Hour); !errors.As(err, &insufficient) {
+		t.Fatalf("Expected InsufficientFundsError but got %T", err)
+	}
+
+	// A partial capture debits its amount and releases the rest of the hold.
+	if err := account.CaptureHold(first.ID, usd("120")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if account.Balance != usd("380") || account.AvailableBalance() != usd("180") {
+		t.Errorf("Expected balance 380.00 USD with 180.00 USD available, got %s with %s",
+			account.Balance, account.AvailableBalance())
+	}
+	ledger := account.Ledger()
+	if last := ledger[len(ledger)-1]; last.Type != EntryCapture || last.Amount != usd("-120") {
+		t.Errorf("Expected a capture of -120.00 USD, got %+v", last)
+	}
+
+	if err := account.ReleaseHold(second.ID); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if account.AvailableBalance() != usd("380") || len(account.Holds()) != 0 {
+		t.Errorf("Expected no holds left, got %+v", account.Holds())
+	}
+}
+
+func TestHoldExpiry(t *testing.T) {
+	clock := newTestClock()
+	account, _ := newBankAccount("ACC", "Owner", usd("100"), Money{}, clock.now)
+	expiring, _ := account.PlaceHold(usd("60"), 30*time.Minute)
+	lasting, _ := account.PlaceHold(usd("30"), 2*time.Hour)
+
+	clock.t = clock.t.Add(time.Hour)
+	if account.AvailableBalance() != usd("70") {
+		t.Errorf("Expected %s available, got %s", usd("70"), account.AvailableBalance())
+	}
+	if holds := account.Holds(); len(holds) != 1 || holds[0] != lasting {
+		t.Errorf("Expected only hold %+v, got %+v", lasting, holds)
+	}
+	if err := account.CaptureHold(expiring.ID, usd("60")); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if err := account.Withdraw(usd("70")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+}
+
+func TestHoldErrors(t *testing.T) {
+	clock := newTestClock()
+	account, _ := newBankAccount(
+		"ACC",
+		"Owner",
+		usd("1000"),
+		Money{},
+		clock.now,
+		WithTransactionLimit(usd("300")),
+		WithDailyLimit(usd("500")),
+	)
+	hold, _ := account.PlaceHold(usd("300"), time.Hour)
+
+	testCases := []struct {
+		name      string
+		op        func() error
+		errorType string
+		wantKind  LimitKind
+	}{
+		{
+			name: "Hold over the transaction limit",
+			op: func() error {
+				_, err := account.PlaceHold(usd("300.01"), time.Hour)
+				return err
+			},
+			errorType: "ExceedsLimitError",
+			wantKind:  TransactionLimit,
+		},
+		{
+			name: "Hold over the daily limit",
+			op: func() error {
+				_, err := account.PlaceHold(usd("200.01"), time.Hour)
+				return err
+			},
+			errorType: "ExceedsLimitError",
+			wantKind:  DailyLimit,
+		},
+		{
+			name:      "Withdrawal over the daily limit with holds",
+			op:        func() error { return account.Withdraw(usd("200.01")) },
+			errorType: "ExceedsLimitError",
+			wantKind:  DailyLimit,
+		},
+		{
+			name: "Negative hold",
+			op: func() error {
+				_, err := account.PlaceHold(usd("-1"), time.Hour)
+				return err
+			},
+			errorType: "NegativeAmountError",
+		},
+		{
+			name: "No duration",
+			op: func() error {
+				_, err := account.PlaceHold(usd("1"), 0)
+				return err
+			},
+			errorType: "AccountError",
+		},
+		{
+			name:      "Capture over the hold",
+			op:        func() error { return account.CaptureHold(hold.ID, usd("300.01")) },
+			errorType: "ExceedsLimitError",
+			wantKind:  HoldLimit,
+		},
+		{
+			name:      "Zero capture",
+			op:        func() error { return account.CaptureHold(hold.ID, usd("0")) },
+			errorType: "AccountError",
+		},
+		{
+			name:      "Capture of an unknown hold",
+			op:        func() error { return account.CaptureHold(42, usd("1")) },
+			errorType: "AccountError",
+		},
+		{
+			name:      "Release of an unknown hold",
+			op:        func() error { return account.ReleaseHold(42) },
+			errorType: "AccountError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.op()
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !strings.Contains(fmt.Sprintf("%T", err), tc.errorType) {
+				t.Errorf("Expected error of type %s but got %T", tc.errorType, err)
+			}
+			var limitErr *ExceedsLimitError
+			if errors.As(err, &limitErr) && limitErr.Kind() != tc.wantKind {
+				t.Errorf("Expected the %s limit, got %s", tc.wantKind, limitErr.Kind())
+			}
+		})
+	}
+
+	if holds := account.Holds(); len(holds) != 1 || holds[0] != hold {
+		t.Errorf("Expected only hold %+v, got %+v", hold, holds)
+	}
+	// The capture was limited when the hold was placed.
+	if err := account.CaptureHold(hold.ID, usd("300")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+}
+
+func TestBankHolds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("500"), Money{})
+
+	open, err := bank.PlaceHold("ACC001", usd("100"), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	captured, _ := bank.PlaceHold("ACC001", usd("50"), 24*time.Hour)
+	if err := bank.CaptureHold("ACC001", captured.ID, usd("30")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	released, _ := bank.PlaceHold("ACC001", usd("20"), 24*time.Hour)
+	if err := bank.CaptureHold("ACC001", released.ID, usd("0")); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if err := bank.ReleaseHold("ACC001", released.ID); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if _, err := bank.PlaceHold("ACC001", usd("400.01"), 24*time.Hour); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	reopened := openTestBank(t, path)
+	alice, _ := reopened.Account("ACC001")
+	if holds := alice.Holds(); len(holds) != 1 || holds[0] != open {
+		t.Errorf("Expected only hold %+v, got %+v", open, holds)
+	}
+	if alice.Balance != usd("470") || alice.AvailableBalance() != usd("370") {
+		t.Errorf("Expected balance 470.00 USD with 370.00 USD available, got %s with %s",
+			alice.Balance, alice.AvailableBalance())
+	}
+	ledger := alice.Ledger()
+	if last := ledger[len(ledger)-1]; last.Type != EntryCapture || last.Amount != usd("-30") {
+		t.Errorf("Expected a capture of -30.00 USD, got %+v", last)
+	}
+
+	// The captures persisted their ledger entries, so new ones follow them.
+	if err := reopened.Deposit("ACC001", usd("10")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := reopened.CaptureHold("ACC001", open.ID, usd("100")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	// Hold IDs are not reused, even those of closed holds.
+	next, err := reopened.PlaceHold("ACC001", usd("1"), time.Hour)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if next.ID != released.ID+1 {
+		t.Errorf("Expected hold ID %d, got %d", released.ID+1, next.ID)
+	}
+
+	alice, _ = openTestBank(t, path).Account("ACC001")
+	if holds := alice.Holds(); len(holds) != 1 || holds[0] != next {
+		t.Errorf("Expected only hold %+v, got %+v", next, holds)
+	}
+	if alice.Balance != usd("380") {
+		t.Errorf("Expected balance %s, got %s", usd("380"), alice.Balance)
+	}
+}
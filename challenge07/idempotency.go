@@ -0,0 +1,209 @@
+package challenge07
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Receipt is the result of an operation made with an idempotency key, which
+// replays of the operation return.
+type Receipt struct {
+	Key string
+	// Operation is the type of the entry that the operation recorded in the
+	// ledger of the account: EntryDeposit, EntryWithdrawal or EntryTransferOut.
+	Operation EntryType
+	AccountID string
+	// Counterparty is the ID of the account that a transfer went to.
+	Counterparty string
+	Amount       Money
+	// EntryID is the ID of the ledger entry of the operation in the account.
+	EntryID int64
+	Time    time.Time
+	// Balance is the balance of the account after the operation.
+	Balance Money
+	// Replayed reports whether the receipt is that of an earlier call.
+	Replayed bool
+}
+
+// complete fills in the receipt from the ledger entry of its operation.
+func (r *Receipt) complete(entry LedgerEntry) {
+	r.EntryID = entry.ID
+	r.Time = entry.Time
+	r.Balance = entry.Balance
+}
+
+// sameOperation reports whether r and o are receipts of the same operation.
+func (r *Receipt) sameOperation(o *Receipt) bool {
+	return r.Operation == o.Operation && r.AccountID == o.AccountID &&
+		r.Counterparty == o.Counterparty && r.Amount == o.Amount
+}
+
+// IdempotencyKeyReusedError occurs when an idempotency key is reused for a different operation.
+type IdempotencyKeyReusedError struct {
+	key string
+}
+
+func (e *IdempotencyKeyReusedError) Error() string {
+	return fmt.Sprintf(
+		"idempotency key %q was already used for a different operation",
+		e.key,
+	)
+}
+
+// DepositIdempotent deposits amount to the account with the given ID, as
+// Deposit does, once per key. Calls with a key that a successful call already
+// used return the receipt of that call instead, or an IdempotencyKeyReusedError
+// if its operation was different. Failed calls do not use up their key, and may
+// be retried.
+func (b *Bank) DepositIdempotent(key, id string, amount Money) (Receipt, error) {
+	return b.once(Receipt{Key: key, Operation: EntryDeposit, AccountID: id, Amount: amount},
+		func(receipt *Receipt) error {
+			return b.deposit(id, amount, receipt)
+		})
+}
+
+// WithdrawIdempotent withdraws amount from the account with the given ID, as
+// Withdraw does, once per key. See DepositIdempotent.
+func (b *Bank) WithdrawIdempotent(key, id string, amount Money) (Receipt, error) {
+	return b.once(Receipt{Key: key, Operation: EntryWithdrawal, AccountID: id, Amount: amount},
+		func(receipt *Receipt) error {
+			return b.withdraw(id, amount, receipt)
+		})
+}
+
+// TransferIdempotent moves amount between the accounts with the given IDs, as
+// Transfer does, once per key. See DepositIdempotent.
+func (b *Bank) TransferIdempotent(key, fromID, toID string, amount Money) (Receipt, error) {
+	return b.once(
+		Receipt{
+			Key:          key,
+			Operation:    EntryTransferOut,
+			AccountID:    fromID,
+			Counterparty: toID,
+			Amount:       amount,
+		},
+		func(receipt *Receipt) error {
+			return b.transfer(fromID, toID, amount, nil, receipt)
+		},
+	)
+}
+
+// once runs the operation of receipt with run, unless a receipt with the same
+// key exists already.
+func (b *Bank) once(receipt Receipt, run func(receipt *Receipt) error) (Receipt, error) {
+	if receipt.Key == "" {
+		return Receipt{}, &AccountError{
+			accountID: receipt.AccountID,
+			message:   "idempotency key is blank",
+		}
+	}
+	unlock := b.keys.lock(receipt.Key)
+	defer unlock()
+
+	stored, err := b.findReceipt(receipt.Key)
+	if err == nil {
+		if !stored.sameOperation(&receipt) {
+			return Receipt{}, &IdempotencyKeyReusedError{key: receipt.Key}
+		}
+		stored.Replayed = true
+		return stored, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Receipt{}, err
+	}
+	if err := run(&receipt); err != nil {
+		return Receipt{}, err
+	}
+	return receipt, nil
+}
+
+func (b *Bank) findReceipt(key string) (Receipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	row := b.db.QueryRowContext(
+		ctx,
+		`SELECT operation, account_id, counterparty, amount, currency, entry_id, time, balance
+		FROM idempotency_keys WHERE key = ?`,
+		key,
+	)
+	r := Receipt{Key: key}
+	var currency Currency
+	var amount, at, balance int64
+	if err := row.Scan(
+		&r.Operation,
+		&r.AccountID,
+		&r.Counterparty,
+		&amount,
+		&currency,
+		&r.EntryID,
+		&at,
+		&balance,
+	); err != nil {
+		return Receipt{}, err
+	}
+	r.Amount = NewMoney(amount, currency)
+	r.Time = time.Unix(0, at).UTC()
+	r.Balance = NewMoney(balance, currency)
+	return r, nil
+}
+
+func insertReceipt(ctx context.Context, tx *sql.Tx, r *Receipt) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO idempotency_keys
+		(key, operation, account_id, counterparty, amount, currency, entry_id, time, balance)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Key,
+		r.Operation,
+		r.AccountID,
+		r.Counterparty,
+		r.Amount.Units(),
+		r.Amount.Currency(),
+		r.EntryID,
+		r.Time.UnixNano(),
+		r.Balance.Units(),
+	)
+	return err
+}
+
+// keyLocks serializes the operations made with the same idempotency key.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu sync.Mutex
+	// waiters counts the holder of the lock and the operations waiting for it.
+	waiters int
+}
+
+// lock locks key, and returns a function that unlocks it.
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*keyLock)
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.waiters++
+	l.mu.Unlock()
+
+	kl.mu.Lock()
+	return func() {
+		kl.mu.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if kl.waiters--; kl.waiters == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package challenge07
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIdempotentOperations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("100"), Money{})
+	_, _ = bank.OpenAccount("ACC002", "Bob", usd("100"), Money{})
+
+	testCases := []struct {
+		name        string
+		op          func(b *Bank) (Receipt, error)
+		wantReceipt Receipt
+	}{
+		{
+			name: "Deposit",
+			op: func(b *Bank) (Receipt, error) {
+				return b.DepositIdempotent("key-1", "ACC001", usd("10"))
+			},
+			wantReceipt: Receipt{
+				Key:       "key-1",
+				Operation: EntryDeposit,
+				AccountID: "ACC001",
+				Amount:    usd("10"),
+				EntryID:   2,
+				Balance:   usd("110"),
+			},
+		},
+		{
+			name: "Withdraw",
+			op: func(b *Bank) (Receipt, error) {
+				return b.WithdrawIdempotent("key-2", "ACC001", usd("20"))
+			},
+			wantReceipt: Receipt{
+				Key:       "key-2",
+				Operation: EntryWithdrawal,
+				AccountID: "ACC001",
+				Amount:    usd("20"),
+				EntryID:   3,
+				Balance:   usd("90"),
+			},
+		},
+		{
+			name: "Transfer",
+			op: func(b *Bank) (Receipt, error) {
+				return b.TransferIdempotent("key-3", "ACC001", "ACC002", usd("30"))
+			},
+			wantReceipt: Receipt{
+				Key:          "key-3",
+				Operation:    EntryTransferOut,
+				AccountID:    "ACC001",
+				Counterparty: "ACC002",
+				Amount:       usd("30"),
+				EntryID:      4,
+				Balance:      usd("60"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			first, err := tc.op(bank)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if first.Time.IsZero() || first.Replayed {
+				t.Errorf("Expected a new receipt, got %+v", first)
+			}
+			want := tc.wantReceipt
+			want.Time = first.Time
+			if first != want {
+				t.Errorf("Expected receipt %+v, got %+v", want, first)
+			}
+
+			// Replays return the original receipt, also once the bank is reopened.
+			want.Replayed = true
+			for _, b := range []*Bank{bank, openTestBank(t, path)} {
+				replay, err := tc.op(b)
+				if err != nil {
+					t.Fatalf("Did not expect error but got: %v", err)
+				}
+				if replay != want {
+					t.Errorf("Expected receipt %+v, got %+v", want, replay)
+				}
+			}
+		})
+	}
+
+	alice, _ := bank.Account("ACC001")
+	bob, _ := bank.Account("ACC002")
+	if alice.Balance != usd("60") || len(alice.Ledger()) != 4 {
+		t.Errorf("Expected operations to apply once, got %s with %d entries",
+			alice.Balance, len(alice.Ledger()))
+	}
+	if bob.Balance != usd("130") {
+		t.Errorf("Expected balance %s, got %s", usd("130"), bob.Balance)
+	}
+}
+
+func TestIdempotentOperationErrors(t *testing.T) {
+	bank := openTestBank(t, filepath.Join(t.TempDir(), "bank.db"))
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("100"), Money{})
+	_, _ = bank.OpenAccount("ACC002", "Bob", usd("100"), Money{})
+	if _, err := bank.TransferIdempotent("used", "ACC001", "ACC002", usd("10")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		op        func() (Receipt, error)
+		errorType string
+	}{
+		{
+			name:      "Blank key",
+			op:        func() (Receipt, error) { return bank.DepositIdempotent("", "ACC001", usd("1")) },
+			errorType: "AccountError",
+		},
+		{
+			name: "Different operation",
+			op: func() (Receipt, error) {
+				return bank.DepositIdempotent("used", "ACC001", usd("10"))
+			},
+			errorType: "IdempotencyKeyReusedError",
+		},
+		{
+			name: "Different amount",
+			op: func() (Receipt, error) {
+				return bank.TransferIdempotent("used", "ACC001", "ACC002", usd("11"))
+			},
+			errorType: "IdempotencyKeyReusedError",
+		},
+		{
+			name: "Different counterparty",
+			op: func() (Receipt, error) {
+				return bank.TransferIdempotent("used", "ACC002", "ACC001", usd("10"))
+			},
+			errorType: "IdempotencyKeyReusedError",
+		},
+		{
+			name: "Failed operation",
+			op: func() (Receipt, error) {
+				return bank.WithdrawIdempotent("failed", "ACC001", usd("1000"))
+			},
+			errorType: "InsufficientFundsError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.op()
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !strings.Contains(fmt.Sprintf("%T", err), tc.errorType) {
+				t.Errorf("Expected error of type %s but got %T", tc.errorType, err)
+			}
+		})
+	}
+
+	// A failed operation does not use up its key.
+	receipt, err := bank.WithdrawIdempotent("failed", "ACC001", usd("50"))
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if receipt.Replayed || receipt.Balance != usd("40") {
+		t.Errorf("Expected a new receipt with balance %s, got %+v", usd("40"), receipt)
+	}
+}
+
+func TestIdempotentConcurrentRetries(t *testing.T) {
+	bank := openTestBank(t, filepath.Join(t.TempDir(), "bank.db"))
+	_, _ = bank.OpenAccount("ACC001", "Alice", usd("100"), Money{})
+
+	var wg sync.WaitGroup
+	receipts := make([]Receipt, 10)
+	for i := range receipts {
+		wg.Go(func() {
+			receipt, err := bank.DepositIdempotent("retried", "ACC001", usd("5"))
+			if err != nil {
+				t.Errorf("Did not expect error but got: %v", err)
+			}
+			receipts[i] = receipt
+		})
+	}
+	wg.Wait()
+
+	replayed := 0
+	for _, r := range receipts {
+		if r.Replayed {
+			replayed++
+		}
+		if r.EntryID != 2 || r.Balance != usd("105") {
+			t.Errorf("Expected the receipt of the first deposit, got %+v", r)
+		}
+	}
+	if replayed != len(receipts)-1 {
+		t.Errorf("Expected %d replays, got %d", len(receipts)-1, replayed)
+	}
+	alice, _ := bank.Account("ACC001")
+	if alice.Balance != usd("105") {
+		t.Errorf("Expected balance %s, got %s", usd("105"), alice.Balance)
+	}
+}
@@ -0,0 +1,115 @@
+package challenge07
+
+import (
+	"slices"
+	"time"
+)
+
+// EntryType is the kind of operation that a ledger entry records.
+type EntryType string
+
+// Entry types.
+const (
+	EntryOpening     EntryType = "opening"
+	EntryDeposit     EntryType = "deposit"
+	EntryWithdrawal  EntryType = "withdrawal"
+	EntryTransferIn  EntryType = "transfer_in"
+	EntryTransferOut EntryType = "transfer_out"
+	EntryCapture     EntryType = "capture"
+)
+
+// LedgerEntry records a change to the balance of an account. Entries are never
+// modified once recorded.
+type LedgerEntry struct {
+	// ID numbers the entries of an account from 1, in the order they were
+	// recorded.
+	ID   int64
+	Type EntryType
+	// Amount is the change in balance: negative for withdrawals, outgoing
+	// transfers and captures of holds.
+	Amount Money
+	// Counterparty is the ID of the other account of a transfer.
+	Counterparty string
+	Time         time.Time
+	// Balance is the balance of the account after the entry.
+	Balance Money
+	// Exchange is the currency exchange of a transfer between accounts in
+	// different currencies, recorded in both of their entries. It is zero for
+	// other entries.
+	Exchange Exchange
+}
+
+// Statement lists the ledger entries of an account over a period, with the
+// balances before and after it.
+type Statement struct {
+	AccountID string
+	// From and To bound the period, From included and To excluded.
+	From, To       time.Time
+	OpeningBalance Money
+	// Entries are the entries of the period, whose Balance is the running
+	// balance.
+	Entries        []LedgerEntry
+	ClosingBalance Money
+}
+
+// Ledger returns the entries of the account, oldest first. The first entry
+// records the opening of the account with its initial balance.
+func (a *BankAccount) Ledger() []LedgerEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.ledger)
+}
+
+// Statement returns the statement of the account for the entries recorded from
+// from, included, to to, excluded. The opening balance of a period that starts
+// before the account was opened is zero.
+func (a *BankAccount) Statement(from, to time.Time) Statement {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return statement(a.ID, a.currency, a.ledger, from, to)
+}
+
+// statement returns the statement of the ledger of an account for the entries
+// recorded from from, included, to to, excluded.
+func statement(
+	accountID string,
+	currency Currency,
+	ledger []LedgerEntry,
+	from, to time.Time,
+) Statement {
+	statement := Statement{
+		AccountID:      accountID,
+		From:           from,
+		To:             to,
+		OpeningBalance: NewMoney(0, currency),
+	}
+	for _, entry := range ledger {
+		switch {
+		case entry.Time.Before(from):
+			statement.OpeningBalance = entry.Balance
+		case entry.Time.Before(to):
+			statement.Entries = append(statement.Entries, entry)
+		}
+	}
+	statement.ClosingBalance = statement.OpeningBalance
+	if n := len(statement.Entries); n > 0 {
+		statement.ClosingBalance = statement.Entries[n-1].Balance
+	}
+	return statement
+}
+
+// record appends entry, which took the balance to the current one, to the
+// ledger, numbering it.
+func (a *BankAccount) record(entry LedgerEntry) {
+	entry.ID = int64(len(a.ledger)) + 1
+	entry.Balance = a.Balance
+	a.ledger = append(a.ledger, entry)
+}
+
+// clock returns the current time, as the account sees it.
+func (a *BankAccount) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,172 @@
+package challenge07
+
+import (
+	"testing"
+	"time"
+)
+
+// testClock is a clock that advances by a minute on every reading.
+type testClock struct {
+	t time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	c.t = c.t.Add(time.Minute)
+	return c.t
+}
+
+func TestLedger(t *testing.T) {
+	clock := newTestClock()
+	source, _ := newBankAccount("SRC", "Source", usd("100"), usd("10"), clock.now)
+	target, _ := newBankAccount("TGT", "Target", usd("0"), Money{}, clock.now)
+
+	if err := source.Deposit(usd("50")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := source.Withdraw(usd("500")); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if err := source.Withdraw(usd("20.50")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := source.Transfer(usd("30"), target); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	want := []LedgerEntry{
+		{ID: 1, Type: EntryOpening, Amount: usd("100"), Balance: usd("100")},
+		{ID: 2, Type: EntryDeposit, Amount: usd("50"), Balance: usd("150")},
+		{ID: 3, Type: EntryWithdrawal, Amount: usd("-20.50"), Balance: usd("129.50")},
+		{
+			ID:           4,
+			Type:         EntryTransferOut,
+			Amount:       usd("-30"),
+			Counterparty: "TGT",
+			Balance:      usd("99.50"),
+		},
+	}
+	wantMinutes := []int{1, 3, 5, 6}
+	got := source.Ledger()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		want[i].Time = start.Add(time.Duration(wantMinutes[i]) * time.Minute)
+		if got[i] != want[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	targetLedger := target.Ledger()
+	wantIn := LedgerEntry{
+		ID:           2,
+		Type:         EntryTransferIn,
+		Amount:       usd("30"),
+		Counterparty: "SRC",
+		Time:         want[3].Time,
+		Balance:      usd("30"),
+	}
+	if len(targetLedger) != 2 || targetLedger[1] != wantIn {
+		t.Errorf("Expected target entry %+v, got %+v", wantIn, targetLedger)
+	}
+
+	// The returned ledger is a copy.
+	got[0].Amount = usd("1")
+	if source.Ledger()[0].Amount != usd("100") {
+		t.Error("Expected the ledger not to be modifiable")
+	}
+}
+
+func TestStatement(t *testing.T) {
+	clock := newTestClock()
+	// Opened at 09:01, with deposits from 09:02 to 09:05.
+	account, _ := newBankAccount("ACC", "Owner", usd("100"), Money{}, clock.now)
+	for _, amount := range []string{"10", "20", "30", "40"} {
+		if err := account.Deposit(usd(amount)); err != nil {
+			t.Fatalf("Did not expect error but got: %v", err)
+		}
+	}
+	at := func(minute int) time.Time {
+		return time.Date(2024, 1, 1, 9, minute, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name        string
+		from, to    time.Time
+		wantOpening Money
+		wantClosing Money
+		wantIDs     []int64
+	}{
+		{
+			name:        "Everything",
+			from:        at(0),
+			to:          at(10),
+			wantOpening: usd("0"),
+			wantClosing: usd("200"),
+			wantIDs:     []int64{1, 2, 3, 4, 5},
+		},
+		{
+			name:        "Middle",
+			from:        at(3),
+			to:          at(5),
+			wantOpening: usd("110"),
+			wantClosing: usd("160"),
+			wantIDs:     []int64{3, 4},
+		},
+		{
+			name:        "Empty period",
+			from:        at(6),
+			to:          at(8),
+			wantOpening: usd("200"),
+			wantClosing: usd("200"),
+		},
+		{
+			name:        "Before opening",
+			from:        at(0),
+			to:          at(1),
+			wantOpening: usd("0"),
+			wantClosing: usd("0"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			statement := account.Statement(tc.from, tc.to)
+			if statement.AccountID != "ACC" || statement.From != tc.from || statement.To != tc.to {
+				t.Errorf("Unexpected statement header %+v", statement)
+			}
+			if statement.OpeningBalance != tc.wantOpening {
+				t.Errorf(
+					"Expected opening balance %s, got %s",
+					tc.wantOpening,
+					statement.OpeningBalance,
+				)
+			}
+			if statement.ClosingBalance != tc.wantClosing {
+				t.Errorf(
+					"Expected closing balance %s, got %s",
+					tc.wantClosing,
+					statement.ClosingBalance,
+				)
+			}
+			if len(statement.Entries) != len(tc.wantIDs) {
+				t.Fatalf("Expected %d entries, got %d", len(tc.wantIDs), len(statement.Entries))
+			}
+			running := statement.OpeningBalance
+			for i, entry := range statement.Entries {
+				if entry.ID != tc.wantIDs[i] {
+					t.Errorf("Expected entry %d, got %d", tc.wantIDs[i], entry.ID)
+				}
+				running = running.Add(entry.Amount)
+				if entry.Balance != running {
+					t.Errorf("Expected running balance %s, got %s", running, entry.Balance)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package challenge07
+
+import "time"
+
+// Windows of the rolling withdrawal limits.
+const (
+	dailyWindow   = 24 * time.Hour
+	monthlyWindow = 30 * dailyWindow
+)
+
+// LimitKind names a limit of an account.
+type LimitKind string
+
+// Limit kinds.
+const (
+	TransactionLimit LimitKind = "transaction"
+	DailyLimit       LimitKind = "daily withdrawal"
+	MonthlyLimit     LimitKind = "monthly withdrawal"
+	// HoldLimit is the amount of a hold, which its capture may not exceed.
+	HoldLimit LimitKind = "hold"
+)
+
+// Limits is the policy of an account for the amounts of its transactions, in
+// the currency of the account.
+type Limits struct {
+	// Transaction limits each deposit, withdrawal, outgoing transfer and hold.
+	// Zero means MaxTransactionAmount.
+	Transaction Money
+	// Daily and Monthly limit the total of the withdrawals, outgoing transfers
+	// and holds over the last 24 hours and 30 days. Zero means no limit.
+	Daily   Money
+	Monthly Money
+	// Overdraft is how far below the minimum balance withdrawals, outgoing
+	// transfers and holds may bring the available balance.
+	Overdraft Money
+}
+
+// AccountOption configures the limits of an account created with
+// NewBankAccount.
+type AccountOption func(*Limits)
+
+// WithTransactionLimit limits each transaction of the account to amount.
+func WithTransactionLimit(amount Money) AccountOption {
+	return func(l *Limits) {
+		l.Transaction = amount
+	}
+}
+
+// WithDailyLimit limits the withdrawals of the account over the last 24 hours
+// to amount.
+func WithDailyLimit(amount Money) AccountOption {
+	return func(l *Limits) {
+		l.Daily = amount
+	}
+}
+
+// WithMonthlyLimit limits the withdrawals of the account over the last 30 days
+// to amount.
+func WithMonthlyLimit(amount Money) AccountOption {
+	return func(l *Limits) {
+		l.Monthly = amount
+	}
+}
+
+// WithOverdraft lets withdrawals bring the balance of the account down to
+// amount below its minimum balance.
+func WithOverdraft(amount Money) AccountOption {
+	return func(l *Limits) {
+		l.Overdraft = amount
+	}
+}
+
+// Limits returns the limits of the account.
+func (a *BankAccount) Limits() Limits {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limits
+}
+
+// SetLimits replaces the limits of the account. It returns an error if any of
+// the limits is negative or in another currency.
+func (a *BankAccount) SetLimits(limits Limits) error {
+	limits, err := a.validateLimits(limits)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.limits = limits
+	return nil
+}
+
+// validateLimits validates limits, and returns them with their zero amounts in
+// the currency of the account and the default transaction limit.
+func (a *BankAccount) validateLimits(limits Limits) (Limits, error) {
+	for _, amount := range []*Money{
+		&limits.Transaction,
+		&limits.Daily,
+		&limits.Monthly,
+		&limits.Overdraft,
+	} {
+		if *amount == (Money{}) {
+			*amount = NewMoney(0, a.currency)
+		}
+		if amount.Currency() != a.currency {
+			return Limits{}, &CurrencyMismatchError{
+				accountID: a.ID,
+				currency:  amount.Currency(),
+				want:      a.currency,
+			}
+		}
+		if amount.IsNegative() {
+			return Limits{}, &NegativeAmountError{accountID: a.ID}
+		}
+	}
+	if limits.Transaction.IsZero() {
+		limits.Transaction = maxTransaction(a.currency)
+	}
+	return limits, nil
+}
+
+// checkLimits checks entry against the limits of the locked account. Captures
+// of holds are not checked, since their holds were when placed.
+func (a *BankAccount) checkLimits(entry LedgerEntry) error {
+	if entry.Type == EntryCapture {
+		return nil
+	}
+	amount := entry.Amount
+	if amount.IsNegative() {
+		amount = amount.Neg()
+	}
+	if entry.Type != EntryTransferIn && amount.Cmp(a.limits.Transaction) > 0 {
+		return a.exceeds(TransactionLimit, amount, a.limits.Transaction)
+	}
+	if entry.Type != EntryWithdrawal && entry.Type != EntryTransferOut {
+		return nil
+	}
+	for _, window := range []struct {
+		kind     LimitKind
+		limit    Money
+		duration time.Duration
+	}{
+		{kind: DailyLimit, limit: a.limits.Daily, duration: dailyWindow},
+		{kind: MonthlyLimit, limit: a.limits.Monthly, duration: monthlyWindow},
+	} {
+		if window.limit.IsZero() {
+			continue
+		}
+		withdrawn, err := a.withdrawn(entry.Time.Add(-window.duration), entry.Time)
+		if err != nil {
+			return err
+		}
+		total, err := withdrawn.CheckedAdd(amount)
+		if err != nil {
+			return err
+		}
+		if total.Cmp(window.limit) > 0 {
+			return a.exceeds(window.kind, amount, window.limit)
+		}
+	}
+	return nil
+}
+
+func (a *BankAccount) exceeds(kind LimitKind, amount, limit Money) error {
+	return &ExceedsLimitError{accountID: a.ID, kind: kind, amount: amount, limit: limit}
+}
+
+// withdrawn returns the total of the withdrawals, outgoing transfers and
+// captures of the locked account after since, and of its holds placed after
+// since that are active at at. It returns an error wrapping ErrInvalidMoney if
+// the total is out of range.
+func (a *BankAccount) withdrawn(since, at time.Time) (Money, error) {
+	total := NewMoney(0, a.currency)
+	var err error
+	for i := len(a.ledger) - 1; i >= 0 && a.ledger[i].Time.After(since); i-- {
+		if t := a.ledger[i].Type; t == EntryWithdrawal || t == EntryTransferOut ||
+			t == EntryCapture {
+			if total, err = total.CheckedSub(a.ledger[i].Amount); err != nil {
+				return Money{}, err
+			}
+		}
+	}
+	for _, h := range a.holds {
+		if h.PlacedAt.After(since) && h.active(at) {
+			if total, err = total.CheckedAdd(h.Amount); err != nil {
+				return Money{}, err
+			}
+		}
+	}
+	return total, nil
+}
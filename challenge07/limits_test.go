@@ -0,0 +1,279 @@
+package challenge07
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAccountLimits(t *testing.T) {
+	type step struct {
+		// wait advances the clock before the operation.
+		wait      time.Duration
+		op        func(a, other *BankAccount) error
+		errorType string
+		wantKind  LimitKind
+		// wantAmount and wantLimit are those of an ExceedsLimitError.
+		wantAmount string
+		wantLimit  string
+	}
+	withdraw := func(amount string) func(a, other *BankAccount) error {
+		return func(a, _ *BankAccount) error { return a.Withdraw(usd(amount)) }
+	}
+
+	testCases := []struct {
+		name  string
+		opts  []AccountOption
+		steps []step
+	}{
+		{
+			name: "Transaction limit",
+			opts: []AccountOption{WithTransactionLimit(usd("100"))},
+			steps: []step{
+				{op: withdraw("100")},
+				{
+					op:         withdraw("100.01"),
+					errorType:  "ExceedsLimitError",
+					wantKind:   TransactionLimit,
+					wantAmount: "100.01",
+					wantLimit:  "100",
+				},
+				{
+					op:         func(a, _ *BankAccount) error { return a.Deposit(usd("100.01")) },
+					errorType:  "ExceedsLimitError",
+					wantKind:   TransactionLimit,
+					wantAmount: "100.01",
+					wantLimit:  "100",
+				},
+			},
+		},
+		{
+			name: "Default transaction limit",
+			steps: []step{
+				{
+					op:         withdraw("10000.01"),
+					errorType:  "ExceedsLimitError",
+					wantKind:   TransactionLimit,
+					wantAmount: "10000.01",
+					wantLimit:  "10000",
+				},
+			},
+		},
+		{
+			name: "Rolling daily limit",
+			opts: []AccountOption{WithDailyLimit(usd("500"))},
+			steps: []step{
+				{op: withdraw("300")},
+				{
+					op: func(a, other *BankAccount) error {
+						return a.Transfer(usd("200"), other)
+					},
+				},
+				{
+					wait:       23 * time.Hour,
+					op:         withdraw("0.01"),
+					errorType:  "ExceedsLimitError",
+					wantKind:   DailyLimit,
+					wantAmount: "0.01",
+					wantLimit:  "500",
+				},
+				// Deposits do not count towards withdrawal limits.
+				{op: func(a, _ *BankAccount) error { return a.Deposit(usd("1000")) }},
+				// The first withdrawal leaves the window.
+				{wait: time.Hour, op: withdraw("300")},
+			},
+		},
+		{
+			name: "Rolling monthly limit",
+			opts: []AccountOption{WithDailyLimit(usd("400")), WithMonthlyLimit(usd("1000"))},
+			steps: []step{
+				{op: withdraw("400")},
+				{wait: 10 * 24 * time.Hour, op: withdraw("400")},
+				{
+					wait:       10 * 24 * time.Hour,
+					op:         withdraw("200.01"),
+					errorType:  "ExceedsLimitError",
+					wantKind:   MonthlyLimit,
+					wantAmount: "200.01",
+					wantLimit:  "1000",
+				},
+				{op: withdraw("200")},
+				{wait: 10 * 24 * time.Hour, op: withdraw("400")},
+			},
+		},
+		{
+			name: "Overdraft",
+			opts: []AccountOption{WithOverdraft(usd("50"))},
+			steps: []step{
+				{op: withdraw("5030")},
+				{op: withdraw("10")},
+				{op: withdraw("0.01"), errorType: "InsufficientFundsError"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newTestClock()
+			a, err := newBankAccount("ACC", "Owner", usd("5000"), usd("10"), clock.now, tc.opts...)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			other, _ := newBankAccount("OTH", "Other", usd("0"), Money{}, clock.now)
+
+			for i, s := range tc.steps {
+				clock.t = clock.t.Add(s.wait)
+				balance := a.Balance
+				err := s.op(a, other)
+				if s.errorType == "" {
+					if err != nil {
+						t.Fatalf("Step %d: did not expect error but got: %v", i, err)
+					}
+					continue
+				}
+				if err == nil {
+					t.Fatalf("Step %d: expected error but got none", i)
+				}
+				if !strings.Contains(fmt.Sprintf("%T", err), s.errorType) {
+					t.Errorf("Step %d: expected error of type %s but got %T", i, s.errorType, err)
+				}
+				var limitErr *ExceedsLimitError
+				if errors.As(err, &limitErr) {
+					if limitErr.Kind() != s.wantKind {
+						t.Errorf(
+							"Step %d: expected the %s limit, got %s",
+							i,
+							s.wantKind,
+							limitErr.Kind(),
+						)
+					}
+					if limitErr.Amount() != usd(s.wantAmount) ||
+						limitErr.Limit() != usd(s.wantLimit) {
+						t.Errorf(
+							"Step %d: expected %s over the limit %s, got %s over %s",
+							i,
+							s.wantAmount,
+							s.wantLimit,
+							limitErr.Amount(),
+							limitErr.Limit(),
+						)
+					}
+				}
+				if a.Balance != balance {
+					t.Errorf("Step %d: expected balance %s, got %s", i, balance, a.Balance)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidLimits(t *testing.T) {
+	testCases := []struct {
+		name      string
+		opts      []AccountOption
+		errorType string
+	}{
+		{
+			name:      "Negative limit",
+			opts:      []AccountOption{WithDailyLimit(usd("-1"))},
+			errorType: "NegativeAmountError",
+		},
+		{
+			name:      "Negative overdraft",
+			opts:      []AccountOption{WithOverdraft(usd("-1"))},
+			errorType: "NegativeAmountError",
+		},
+		{
+			name:      "Other currency",
+			opts:      []AccountOption{WithTransactionLimit(money("100", "EUR"))},
+			errorType: "CurrencyMismatchError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewBankAccount("ACC", "Owner", usd("100"), Money{}, tc.opts...)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !strings.Contains(fmt.Sprintf("%T", err), tc.errorType) {
+				t.Errorf("Expected error of type %s but got %T", tc.errorType, err)
+			}
+
+			account, _ := NewBankAccount("ACC", "Owner", usd("100"), Money{})
+			var limits Limits
+			for _, opt := range tc.opts {
+				opt(&limits)
+			}
+			if err := account.SetLimits(limits); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestDailyLimitConcurrentWithdrawals(t *testing.T) {
+	account, _ := NewBankAccount("ACC", "Owner", usd("1000"), Money{}, WithDailyLimit(usd("100")))
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			_ = account.Withdraw(usd("7"))
+		})
+	}
+	wg.Wait()
+
+	if account.Balance != usd("902") {
+		t.Errorf("Expected 14 withdrawals to a balance of %s, got %s", usd("902"), account.Balance)
+	}
+}
+
+func TestBankLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	bank := openTestBank(t, path)
+	if _, err := bank.OpenAccount(
+		"ACC001",
+		"Alice",
+		usd("1000"),
+		Money{},
+		WithDailyLimit(usd("300")),
+	); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	_, _ = bank.OpenAccount("ACC002", "Bob", usd("1000"), Money{})
+	want := Limits{
+		Transaction: usd("250"),
+		Daily:       usd("300"),
+		Monthly:     usd("2000"),
+		Overdraft:   usd("100"),
+	}
+	if err := bank.SetLimits("ACC001", want); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if err := bank.SetLimits("ACC001", Limits{Daily: usd("-1")}); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	reopened := openTestBank(t, path)
+	alice, _ := reopened.Account("ACC001")
+	if got := alice.Limits(); got != want {
+		t.Errorf("Expected limits %+v, got %+v", want, got)
+	}
+	bob, _ := reopened.Account("ACC002")
+	if got := bob.Limits().Transaction; got != maxTransaction("USD") {
+		t.Errorf("Expected the default transaction limit, got %s", got)
+	}
+
+	if err := reopened.Transfer("ACC001", "ACC002", usd("250")); err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	var limitErr *ExceedsLimitError
+	err := reopened.Withdraw("ACC001", usd("50.01"))
+	if !errors.As(err, &limitErr) || limitErr.Kind() != DailyLimit {
+		t.Errorf("Expected the daily limit to be exceeded, got: %v", err)
+	}
+}
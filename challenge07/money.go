@@ -0,0 +1,224 @@
+package challenge07
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney is returned when parsing an invalid amount of money.
+var ErrInvalidMoney = errors.New("invalid amount of money")
+
+// Currency is an ISO 4217 currency code, such as "USD".
+type Currency string
+
+// Valid reports whether c looks like a currency code: three uppercase letters.
+func (c Currency) Valid() bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// exponent returns the number of digits of the minor unit of c, such as 2 for
+// the cents of "USD".
+func (c Currency) exponent() int {
+	switch c {
+	case "BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG", "RWF", "UGX", "VND",
+		"VUV", "XAF", "XOF", "XPF":
+		return 0
+	case "BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// scale returns the number of minor units in a major unit of c.
+func (c Currency) scale() int64 {
+	return pow10(c.exponent())
+}
+
+func pow10(exp int) int64 {
+	p := int64(1)
+	for range exp {
+		p *= 10
+	}
+	return p
+}
+
+// Money is an exact amount of a currency, held as an integer number of minor
+// units, such as cents, so that arithmetic does not drift like float64 does.
+// The zero Money has no currency.
+type Money struct {
+	units    int64
+	currency Currency
+}
+
+// NewMoney returns units minor units of currency, such as cents for "USD".
+func NewMoney(units int64, currency Currency) Money {
+	return Money{units: units, currency: currency}
+}
+
+// ParseMoney parses a decimal amount of currency, such as "12.34" or "-0.5",
+// which must not have more decimals than the minor unit of currency allows.
+func ParseMoney(s string, currency Currency) (Money, error) {
+	if !currency.Valid() {
+		return Money{}, fmt.Errorf("%w: invalid currency %q", ErrInvalidMoney, currency)
+	}
+	units, err := parseDecimal(s, currency.exponent())
+	if errors.Is(err, strconv.ErrRange) {
+		return Money{}, fmt.Errorf("%w: %q out of range", ErrInvalidMoney, s)
+	}
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q in %s", ErrInvalidMoney, s, currency)
+	}
+	return Money{units: units, currency: currency}, nil
+}
+
+// parseDecimal parses a decimal number with at most exp decimals, such as
+// "-12.34", into an integer number of its smallest unit. It returns
+// strconv.ErrSyntax or strconv.ErrRange if s is invalid.
+func parseDecimal(s string, exp int) (int64, error) {
+	digits, negative := strings.CutPrefix(s, "-")
+	whole, frac, hasFrac := strings.Cut(digits, ".")
+	if !isDigits(whole) || (hasFrac && !isDigits(frac)) || len(frac) > exp {
+		return 0, strconv.ErrSyntax
+	}
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, strconv.ErrRange
+	}
+	minor := int64(0)
+	if frac != "" {
+		minor, _ = strconv.ParseInt(frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	}
+	scale := pow10(exp)
+	if major > (math.MaxInt64-minor)/scale {
+		return 0, strconv.ErrRange
+	}
+	units := major*scale + minor
+	if negative {
+		units = -units
+	}
+	return units, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Units returns the amount in minor units.
+func (m Money) Units() int64 {
+	return m.units
+}
+
+// Currency returns the currency of the amount.
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+// Neg returns the opposite amount.
+func (m Money) Neg() Money {
+	return Money{units: -m.units, currency: m.currency}
+}
+
+// Add returns m + o. It panics if the currencies differ, and wraps around if
+// the sum is out of range: use CheckedAdd for amounts that may be that large.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{units: m.units + o.units, currency: m.currency}
+}
+
+// Sub returns m - o. It panics if the currencies differ, and wraps around if
+// the difference is out of range: use CheckedSub for amounts that may be that
+// large.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{units: m.units - o.units, currency: m.currency}
+}
+
+// CheckedAdd returns m + o, or an error wrapping ErrInvalidMoney if the sum is
+// out of range. It panics if the currencies differ.
+func (m Money) CheckedAdd(o Money) (Money, error) {
+	m.mustMatch(o)
+	if (o.units > 0 && m.units > math.MaxInt64-o.units) ||
+		(o.units < 0 && m.units < math.MinInt64-o.units) {
+		return Money{}, fmt.Errorf("%w: %s + %s out of range", ErrInvalidMoney, m, o)
+	}
+	return Money{units: m.units + o.units, currency: m.currency}, nil
+}
+
+// CheckedSub returns m - o, or an error wrapping ErrInvalidMoney if the
+// difference is out of range. It panics if the currencies differ.
+func (m Money) CheckedSub(o Money) (Money, error) {
+	m.mustMatch(o)
+	if (o.units < 0 && m.units > math.MaxInt64+o.units) ||
+		(o.units > 0 && m.units < math.MinInt64+o.units) {
+		return Money{}, fmt.Errorf("%w: %s - %s out of range", ErrInvalidMoney, m, o)
+	}
+	return Money{units: m.units - o.units, currency: m.currency}, nil
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o. It
+// panics if the currencies differ.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.units < o.units:
+		return -1
+	case m.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String formats the amount with the decimals of its currency, such as
+// "12.30 USD".
+func (m Money) String() string {
+	scale := m.currency.scale()
+	// Negate the quotient and remainder rather than the units, which may be
+	// math.MinInt64.
+	major, minor := m.units/scale, m.units%scale
+	sign := ""
+	if m.units < 0 {
+		sign, major, minor = "-", -major, -minor
+	}
+	exp := m.currency.exponent()
+	if exp == 0 {
+		return fmt.Sprintf("%s%d %s", sign, major, m.currency)
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, major, exp, minor, m.currency)
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.currency != o.currency {
+		panic(fmt.Sprintf("challenge07: mixing currencies %s and %s", m.currency, o.currency))
+	}
+}
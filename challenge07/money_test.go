@@ -0,0 +1,169 @@
+package challenge07
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// usd parses a USD amount, panicking if it is invalid.
+func usd(amount string) Money {
+	m, err := ParseMoney(amount, "USD")
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		input     string
+		currency  Currency
+		wantUnits int64
+		wantStr   string
+	}{
+		{input: "12.34", currency: "USD", wantUnits: 1234, wantStr: "12.34 USD"},
+		{input: "0.1", currency: "USD", wantUnits: 10, wantStr: "0.10 USD"},
+		{input: "7", currency: "EUR", wantUnits: 700, wantStr: "7.00 EUR"},
+		{input: "-0.05", currency: "USD", wantUnits: -5, wantStr: "-0.05 USD"},
+		{input: "1500", currency: "JPY", wantUnits: 1500, wantStr: "1500 JPY"},
+		{input: "1.234", currency: "KWD", wantUnits: 1234, wantStr: "1.234 KWD"},
+		{
+			input:     "92233720368547758.07",
+			currency:  "USD",
+			wantUnits: 9223372036854775807,
+			wantStr:   "92233720368547758.07 USD",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input+" "+string(tc.currency), func(t *testing.T) {
+			m, err := ParseMoney(tc.input, tc.currency)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if m.Units() != tc.wantUnits || m.Currency() != tc.currency {
+				t.Errorf(
+					"Expected %d %s, got %d %s",
+					tc.wantUnits,
+					tc.currency,
+					m.Units(),
+					m.Currency(),
+				)
+			}
+			if m.String() != tc.wantStr {
+				t.Errorf("Expected %q, got %q", tc.wantStr, m.String())
+			}
+		})
+	}
+}
+
+func TestParseMoneyInvalid(t *testing.T) {
+	testCases := []struct {
+		input    string
+		currency Currency
+	}{
+		{input: "", currency: "USD"},
+		{input: "1.", currency: "USD"},
+		{input: ".5", currency: "USD"},
+		{input: "1.234", currency: "USD"},
+		{input: "1.5", currency: "JPY"},
+		{input: "1e3", currency: "USD"},
+		{input: "+1", currency: "USD"},
+		{input: "1,000", currency: "USD"},
+		{input: "92233720368547758.08", currency: "USD"},
+		{input: "1", currency: "usd"},
+		{input: "1", currency: ""},
+	}
+
+	for _, tc := range testCases {
+		if _, err := ParseMoney(tc.input, tc.currency); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("Expected ErrInvalidMoney for %q %q, got %v", tc.input, tc.currency, err)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	total := usd("0")
+	for range 10 {
+		total = total.Add(usd("0.1"))
+	}
+	if total != usd("1") {
+		t.Errorf("Expected 1.00 USD, got %s", total)
+	}
+	if got := usd("1").Sub(usd("1.01")); got != usd("-0.01") || !got.IsNegative() {
+		t.Errorf("Expected -0.01 USD, got %s", got)
+	}
+	if usd("2").Cmp(usd("1.99")) != 1 || usd("1").Cmp(usd("1")) != 0 ||
+		usd("-1").Cmp(usd("0")) != -1 {
+		t.Error("Unexpected comparison result")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected adding different currencies to panic")
+		}
+	}()
+	usd("1").Add(NewMoney(100, "EUR"))
+}
+
+func TestMoneyCheckedArithmetic(t *testing.T) {
+	maxUSD, minUSD := NewMoney(math.MaxInt64, "USD"), NewMoney(math.MinInt64, "USD")
+	one := NewMoney(1, "USD")
+
+	testCases := []struct {
+		name    string
+		op      func() (Money, error)
+		want    Money
+		wantErr bool
+	}{
+		{
+			name: "Sum at the maximum",
+			op:   func() (Money, error) { return maxUSD.Sub(one).CheckedAdd(one) },
+			want: maxUSD,
+		},
+		{
+			name:    "Sum over the maximum",
+			op:      func() (Money, error) { return maxUSD.CheckedAdd(one) },
+			wantErr: true,
+		},
+		{
+			name:    "Sum under the minimum",
+			op:      func() (Money, error) { return minUSD.CheckedAdd(one.Neg()) },
+			wantErr: true,
+		},
+		{
+			name: "Difference at the minimum",
+			op:   func() (Money, error) { return minUSD.Add(one).CheckedSub(one) },
+			want: minUSD,
+		},
+		{
+			name:    "Difference under the minimum",
+			op:      func() (Money, error) { return minUSD.CheckedSub(one) },
+			wantErr: true,
+		},
+		{
+			name:    "Difference over the maximum",
+			op:      func() (Money, error) { return usd("0").CheckedSub(minUSD) },
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.op()
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidMoney) {
+					t.Errorf("Expected ErrInvalidMoney but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package challenge07
+
+import (
+	"slices"
+	"time"
+)
+
+// AccountSnapshot is a read-only copy of an account of a Bank, as it was when
+// taken. Changes to the account go through the bank, which persists them.
+type AccountSnapshot struct {
+	ID         string
+	Owner      string
+	Balance    Money
+	MinBalance Money
+	currency   Currency
+	available  Money
+	limits     Limits
+	ledger     []LedgerEntry
+	holds      []Hold
+}
+
+// snapshot returns a snapshot of the account.
+func (a *BankAccount) snapshot() AccountSnapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at := a.clock()
+	return AccountSnapshot{
+		ID:         a.ID,
+		Owner:      a.Owner,
+		Balance:    a.Balance,
+		MinBalance: a.MinBalance,
+		currency:   a.currency,
+		available:  a.Balance.Sub(a.held(at)),
+		limits:     a.limits,
+		ledger:     slices.Clone(a.ledger),
+		holds:      a.activeHolds(at),
+	}
+}
+
+// Currency returns the currency of the account.
+func (s AccountSnapshot) Currency() Currency {
+	return s.currency
+}
+
+// AvailableBalance returns the balance of the account less the amounts on hold.
+func (s AccountSnapshot) AvailableBalance() Money {
+	return s.available
+}
+
+// Limits returns the limits of the account.
+func (s AccountSnapshot) Limits() Limits {
+	return s.limits
+}
+
+// Ledger returns the entries of the account, oldest first.
+func (s AccountSnapshot) Ledger() []LedgerEntry {
+	return slices.Clone(s.ledger)
+}
+
+// Holds returns the active holds of the account, oldest first.
+func (s AccountSnapshot) Holds() []Hold {
+	return slices.Clone(s.holds)
+}
+
+// Statement returns the statement of the account for the entries recorded from
+// from, included, to to, excluded, as BankAccount.Statement does.
+func (s AccountSnapshot) Statement(from, to time.Time) Statement {
+	return statement(s.ID, s.currency, s.ledger, from, to)
+}